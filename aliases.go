@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+)
+
+const CvePrefix = "CVE-"
+
+// normalizeAliases replaces GHSA and vendor advisory ids by the CVE id anchore
+// links them to, so the same issue is reported under one evaluation code. The
+// replaced id and the other linked ids are kept as aliases.
+func normalizeAliases(requestId string, vulnList []scan.VulnerabilityDetail) {
+	for i := range vulnList {
+		v := &vulnList[i]
+		ids := linkedIds(*v)
+		canonical := v.CveId
+		if !isCve(canonical) {
+			for _, id := range ids {
+				if isCve(id) {
+					canonical = id
+					break
+				}
+			}
+		}
+		if canonical != v.CveId {
+			log.Debug(requestId).Msgf("Reporting %s as %s", v.CveId, canonical)
+		}
+		aliases := []string{}
+		for _, id := range append([]string{v.CveId}, ids...) {
+			if !strings.EqualFold(id, canonical) && !containsFold(aliases, id) {
+				aliases = append(aliases, id)
+			}
+		}
+		v.CveId = canonical
+		v.Aliases = aliases
+	}
+}
+
+// linkedIds are the ids of the nvd and vendor records of a finding, in a stable order
+func linkedIds(v scan.VulnerabilityDetail) []string {
+	var ids []string
+	for _, nvd := range v.NvdData {
+		if len(nvd.Id) > 0 {
+			ids = append(ids, nvd.Id)
+		}
+	}
+	for _, vendor := range v.VendorData {
+		if len(vendor.Id) > 0 {
+			ids = append(ids, vendor.Id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func isCve(id string) bool {
+	return strings.HasPrefix(strings.ToUpper(id), CvePrefix)
+}
+
+func isAliased(vulnList []scan.VulnerabilityDetail) bool {
+	for _, v := range vulnList {
+		if len(v.Aliases) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// addAliases adds the aliases column when a finding was reported under other ids
+func addAliases(reqId string, evalMap map[string]*domain.Evaluation, hasAliases bool) {
+	if !hasAliases {
+		return
+	}
+	for _, eval := range evalMap {
+		appendDetailColumns(reqId, eval, []string{"Aliases"}, []string{"csv"}, []string{Summary},
+			func(v scan.VulnerabilityDetail) []string {
+				return []string{strings.Join(v.Aliases, ",")}
+			})
+	}
+}
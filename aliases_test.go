@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeAliases(t *testing.T) {
+	log.Debug().Msg("Inside TestNormalizeAliases - Enter")
+	vulnList := []scan.VulnerabilityDetail{
+		{CveId: "GHSA-7g45-4rm6-3mm3", NvdData: []scan.NvdData{{Id: "CVE-2023-2976"}}},
+		{CveId: "CVE-2022-1304", NvdData: []scan.NvdData{{Id: "CVE-2022-1304"}}},
+		{CveId: "ALAS-2023-1234", VendorData: []scan.VendorData{{Id: "ALAS-2023-1234"}}},
+		{CveId: "DSA-5123-1", NvdData: []scan.NvdData{{Id: "CVE-2022-2"}, {Id: "CVE-2022-1"}}},
+	}
+	normalizeAliases("123", vulnList)
+	assert.Equal(t, "CVE-2023-2976", vulnList[0].CveId)
+	assert.Equal(t, []string{"GHSA-7g45-4rm6-3mm3"}, vulnList[0].Aliases)
+	assert.Equal(t, "CVE-2022-1304", vulnList[1].CveId)
+	assert.Empty(t, vulnList[1].Aliases)
+	assert.Equal(t, "ALAS-2023-1234", vulnList[2].CveId)
+	assert.Empty(t, vulnList[2].Aliases)
+	assert.Equal(t, "CVE-2022-1", vulnList[3].CveId)
+	assert.Equal(t, []string{"DSA-5123-1", "CVE-2022-2"}, vulnList[3].Aliases)
+	log.Debug().Msg("Inside TestNormalizeAliases - Exit")
+}
+
+func TestBuildEvaluationsAliases(t *testing.T) {
+	log.Debug().Msg("Inside TestBuildEvaluationsAliases - Enter")
+	var vulnerabilityList []scan.VulnerabilityDetail
+	vulnerabilitiesByte, _ := os.ReadFile("testdata/getVulnerabilities.json")
+	json.Unmarshal(vulnerabilitiesByte, &vulnerabilityList)
+	ghsa := vulnerabilityList[0]
+	for _, v := range vulnerabilityList {
+		if v.CveId == "GHSA-7g45-4rm6-3mm3" {
+			ghsa = v
+		}
+	}
+	// the same guava issue reported once more under its CVE id
+	cve := ghsa
+	cve.CveId = "CVE-2023-2976"
+	vulnerabilityList = append(vulnerabilityList, cve)
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+
+	settings := AnalysisSettings{EvaluationMode: VulnerabilityMode, SuppressionAction: SuppressionFlag,
+		Suppressions: []Suppression{{CveId: "GHSA-c4r9-r8fh-9vj2", Owner: "platform-team", Justification: "not reachable", Expires: "2099-12-31"}}}
+	evalList, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil, settings)
+	assert.Nil(t, err)
+	codes := map[string]int{}
+	for _, eval := range evalList {
+		if getCategory(eval) == RemediationCategory {
+			continue
+		}
+		codes[eval.Code]++
+		if eval.Code == "CVE-2023-2976" {
+			aliasColumn := indexOf(eval.DetailHeaders, "Aliases")
+			assert.Equal(t, 1, len(eval.Failures[0].Details))
+			assert.Equal(t, "GHSA-7g45-4rm6-3mm3", eval.Failures[0].Details[0].Data[aliasColumn])
+			assert.Contains(t, string(eval.BaseData), "GHSA-7g45-4rm6-3mm3")
+		}
+		if eval.Code == "CVE-2022-38749/"+SuppressedCategory {
+			assert.Equal(t, SuppressedCategory, getCategory(eval))
+		}
+	}
+	assert.Equal(t, 1, codes["CVE-2023-2976"])
+	assert.Equal(t, 1, codes["CVE-2022-38749/"+SuppressedCategory])
+	assert.Zero(t, codes["CVE-2022-38749"])
+	assert.Zero(t, codes["GHSA-7g45-4rm6-3mm3"])
+	log.Debug().Msg("Inside TestBuildEvaluationsAliases - Exit")
+}
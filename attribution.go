@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+)
+
+// attributeBaseImage tags every finding of the base image aware listing as
+// inherited from the base image or introduced by the image. Nothing is tagged
+// when anchore could not tell, e.g. it knows no base image of the image.
+func attributeBaseImage(requestId string, vulnList *[]scan.VulnerabilityDetail) {
+	for _, v := range *vulnList {
+		if v.InheritedFromBase == nil {
+			log.Debug(requestId).Msgf("Anchore did not compare the image with a base image, findings are not attributed")
+			return
+		}
+	}
+	inherited := 0
+	for i := range *vulnList {
+		v := &(*vulnList)[i]
+		if *v.InheritedFromBase {
+			v.Origin = InheritedOrigin
+			inherited++
+		} else {
+			v.Origin = IntroducedOrigin
+		}
+	}
+	log.Info(requestId).Msgf("%d of %d findings inherited from the base image", inherited, len(*vulnList))
+}
+
+func findingKey(v scan.VulnerabilityDetail) string {
+	return v.CveId + "|" + packageKey(v)
+}
+
+func isAttributed(vulnList []scan.VulnerabilityDetail) bool {
+	for _, v := range vulnList {
+		if len(v.Origin) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// addOrigin adds the origin column when the findings have been attributed
+func addOrigin(reqId string, evalMap map[string]*domain.Evaluation, hasOrigin bool) {
+	if !hasOrigin {
+		return
+	}
+	for _, eval := range evalMap {
+		appendDetailColumns(reqId, eval, []string{"Origin"}, []string{String}, []string{Summary},
+			func(v scan.VulnerabilityDetail) []string {
+				return []string{v.Origin}
+			})
+	}
+}
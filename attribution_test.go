@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/testdata"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockBaseImage serves the vulnerability list, the base image aware listing
+// reports the first baseCount findings as inherited from the base image
+func mockBaseImage(t *testing.T, baseCount int) {
+	file, err := os.ReadFile("testdata/getVulnerabilities.json")
+	assert.Nil(t, err)
+	var raw []map[string]interface{}
+	assert.Nil(t, json.Unmarshal(file, &raw))
+	for i := range raw {
+		raw[i]["inheritedFromBase"] = i < baseCount
+	}
+	baseFile, err := json.Marshal(raw)
+	assert.Nil(t, err)
+	testdata.GetVulnerabilitiesMock = func(ctx context.Context, requestId string, imageName string, baseImage bool) ([]byte, error) {
+		if baseImage {
+			return baseFile, nil
+		}
+		return file, nil
+	}
+	scan.IAnchore = testdata.HttpMock1{}
+}
+
+func TestAttributeBaseImage(t *testing.T) {
+	log.Debug().Msg("Inside TestAttributeBaseImage - Enter")
+	mockBaseImage(t, 10)
+	vulnList, err := scan.GetVulnerabilities(context.Background(), "1234", "localhost:v1.0.1", true)
+	assert.Nil(t, err)
+
+	attributeBaseImage("1234", &vulnList)
+	for i, v := range vulnList {
+		if i < 10 {
+			assert.Equal(t, InheritedOrigin, v.Origin)
+		} else {
+			assert.Equal(t, IntroducedOrigin, v.Origin)
+		}
+	}
+	assert.True(t, isAttributed(vulnList))
+	log.Debug().Msg(" TestAttributeBaseImage - Exit")
+}
+
+func TestAttributeBaseImageSkipped(t *testing.T) {
+	log.Debug().Msg("Inside TestAttributeBaseImageSkipped - Enter")
+	mockBaseImage(t, 10)
+
+	// the listing without base image does not tell the origin
+	vulnList, err := scan.GetVulnerabilities(context.Background(), "1234", "localhost:v1.0.1", false)
+	assert.Nil(t, err)
+	attributeBaseImage("1234", &vulnList)
+	assert.False(t, isAttributed(vulnList))
+
+	// neither does a listing anchore could only partly compare
+	vulnList, err = scan.GetVulnerabilities(context.Background(), "1234", "localhost:v1.0.1", true)
+	assert.Nil(t, err)
+	vulnList[3].InheritedFromBase = nil
+	attributeBaseImage("1234", &vulnList)
+	assert.False(t, isAttributed(vulnList))
+	log.Debug().Msg(" TestAttributeBaseImageSkipped - Exit")
+}
+
+func TestProcessAssetsBaseImage(t *testing.T) {
+	log.Debug().Msg("Inside TestProcessAssetsBaseImage - Enter")
+	InitConfig()
+	testdata.MockGetRegistries("testdata/getregistries.json")
+	testdata.MockGetImage("testdata/getimage.json")
+	mockBaseImage(t, 10)
+	listVulnerabilities := testdata.GetVulnerabilitiesMock
+	var calls []bool
+	testdata.GetVulnerabilitiesMock = func(ctx context.Context, requestId string, imageName string, baseImage bool) ([]byte, error) {
+		calls = append(calls, baseImage)
+		return listVulnerabilities(ctx, requestId, imageName, baseImage)
+	}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "aws_ecr_repo", Identifier: "arn:aws:ecr:us-east-1:1234567:repository/test/plugin-test"}}
+	profile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	settings := AnalysisSettings{EvaluationMode: VulnerabilityMode, BaseImageAttribution: true, Retry: scan.RetryPolicy{Count: 1}}
+
+	// one base image aware listing, no second listing of a parent image
+	checks, err := processAssets(context.Background(), "1234", profile.Identifier, asset, profile, settings)
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true}, calls)
+	for _, check := range checks {
+		if getCategory(check) == VulnerabilityCategory {
+			assert.GreaterOrEqual(t, indexOf(check.DetailHeaders, "Origin"), 0)
+		}
+	}
+
+	calls = nil
+	settings.BaseImageAttribution = false
+	checks, err = processAssets(context.Background(), "1234", profile.Identifier, asset, profile, settings)
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{false}, calls)
+	for _, check := range checks {
+		assert.Equal(t, -1, indexOf(check.DetailHeaders, "Origin"))
+	}
+	log.Debug().Msg(" TestProcessAssetsBaseImage - Exit")
+}
+
+func TestBuildEvaluationsSeparateBaseImage(t *testing.T) {
+	log.Debug().Msg("Inside TestBuildEvaluationsSeparateBaseImage - Enter")
+	mockBaseImage(t, 10)
+	vulnList, err := scan.GetVulnerabilities(context.Background(), "1234", "localhost:v1.0.1", true)
+	assert.Nil(t, err)
+	attributeBaseImage("1234", &vulnList)
+
+	settings := AnalysisSettings{EvaluationMode: VulnerabilityMode, SuppressionAction: SuppressionFlag, SeparateBaseImage: true}
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+	evalList, err := buildEvaluations("1234", &vulnList, asset, assetProfile, "localhost:v1.0.1", nil, settings)
+	assert.Nil(t, err)
+
+	categories := map[string]int{}
+	for _, eval := range evalList {
+		category := getCategory(eval)
+		categories[category]++
+		if category == RemediationCategory {
+			continue
+		}
+		originColumn := indexOf(eval.DetailHeaders, "Origin")
+		assert.GreaterOrEqual(t, originColumn, 0)
+		for _, failure := range eval.Failures {
+			for _, row := range failure.Details {
+				if category == BaseImageCategory {
+					assert.Equal(t, InheritedOrigin, row.Data[originColumn])
+				} else {
+					assert.Equal(t, IntroducedOrigin, row.Data[originColumn])
+				}
+			}
+		}
+	}
+	assert.Greater(t, categories[BaseImageCategory], 0)
+	assert.Greater(t, categories[VulnerabilityCategory], 0)
+	log.Debug().Msg(" TestBuildEvaluationsSeparateBaseImage - Exit")
+}
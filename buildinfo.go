@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+)
+
+const VersionRequestId = "version-check"
+
+// DefaultVersion is reported by builds without git details, like go run
+const DefaultVersion = "0.0.1"
+
+// set at build time with -ldflags -X, see the Dockerfile
+var (
+	GitCommitId string
+	BuildDate   string
+	GitDescribe string
+)
+
+// anchorectlVersion is the version of the anchorectl detected at startup
+var anchorectlVersion string
+
+// pluginVersion is the git description of the build without its v prefix
+func pluginVersion() string {
+	version := strings.TrimPrefix(strings.TrimSpace(GitDescribe), "v")
+	if len(version) == 0 {
+		return DefaultVersion
+	}
+	return version
+}
+
+// manifestVersion is the plugin version with the anchorectl version as build
+// metadata, e.g. 1.4.0+anchorectl.1.8.0
+func manifestVersion() string {
+	if len(anchorectlVersion) == 0 {
+		return pluginVersion()
+	}
+	return pluginVersion() + "+anchorectl." + anchorectlVersion
+}
+
+// logBuildInfo logs the build details and detects the anchorectl version
+func logBuildInfo() {
+	log.Info().Msgf("AnchorePlugin version %s, commit %s, built %s", pluginVersion(), valueOrUnknown(GitCommitId), valueOrUnknown(BuildDate))
+	version, err := scan.GetAnchorectlVersion(VersionRequestId)
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not detect the anchorectl version")
+		return
+	}
+	anchorectlVersion = strings.TrimPrefix(version, "v")
+	log.Info().Msgf("Using anchorectl version %s", anchorectlVersion)
+}
+
+func valueOrUnknown(value string) string {
+	if len(value) == 0 {
+		return "unknown"
+	}
+	return value
+}
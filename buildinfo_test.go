@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestVersion(t *testing.T) {
+	log.Debug().Msg("Inside TestManifestVersion - Enter")
+	defer func(describe, anchorectl string) {
+		GitDescribe, anchorectlVersion = describe, anchorectl
+	}(GitDescribe, anchorectlVersion)
+
+	GitDescribe, anchorectlVersion = "", ""
+	assert.Equal(t, DefaultVersion, manifestVersion())
+
+	GitDescribe = "v1.4.0"
+	assert.Equal(t, "1.4.0", manifestVersion())
+
+	GitDescribe = "v1.4.0-3-g1c64678"
+	anchorectlVersion = "1.8.0"
+	assert.Equal(t, "1.4.0-3-g1c64678+anchorectl.1.8.0", manifestVersion())
+	log.Debug().Msg("Inside TestManifestVersion - Exit")
+}
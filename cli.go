@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	service "github.com/cloudbees-compliance/chplugin-go/v0.4.0/servicev0_4_0"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/utilities"
+)
+
+const ScanCommand = "scan"
+
+// exit codes of the commands
+const (
+	ExitOk    = 0
+	ExitError = 1
+	ExitUsage = 2
+)
+
+// ScanOptions are the flags of the scan command
+type ScanOptions struct {
+	SubType           string
+	Identifier        string
+	Tag               string
+	Digest            string
+	AccountUuid       string
+	CredentialsSecret string
+	Settings          string
+	Output            string
+	Cred              scan.AccountCred
+}
+
+// runCommand runs the command of the arguments, it reports false when there
+// is none and the plugin serves the hub
+func runCommand(args []string, stdout, stderr io.Writer) (bool, int) {
+	if len(args) == 0 || args[0] != ScanCommand {
+		return false, ExitOk
+	}
+	return true, scanCommand(args[1:], stdout, stderr)
+}
+
+// scanCommand analyses one image like an ExecuteRequest of the hub would and
+// prints the evaluations as JSON
+func scanCommand(args []string, stdout, stderr io.Writer) int {
+	options, err := parseScanOptions(args, stderr)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(stderr, err)
+		}
+		return ExitUsage
+	}
+	req, asset, err := options.request()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitUsage
+	}
+	checks, err := scanAsset(context.Background(), req, asset)
+	if err != nil {
+		fmt.Fprintf(stderr, "scan failed: %v\n", err)
+		return ExitError
+	}
+	out := stdout
+	if len(options.Output) > 0 {
+		file, err := os.Create(options.Output)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return ExitError
+		}
+		defer file.Close()
+		out = file
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(checks); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitError
+	}
+	return ExitOk
+}
+
+func parseScanOptions(args []string, stderr io.Writer) (*ScanOptions, error) {
+	options := &ScanOptions{}
+	flags := flag.NewFlagSet(ScanCommand, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&options.SubType, "subtype", "", "asset subtype: dockerhub_repo, artifactory_repo, nexus_repo_binary or aws_ecr_repo")
+	flags.StringVar(&options.Identifier, "identifier", "", "asset identifier as known by the hub, e.g. the repository ARN of aws_ecr_repo")
+	flags.StringVar(&options.Tag, "tag", "", "image tag")
+	flags.StringVar(&options.Digest, "digest", "", "image digest, takes precedence over the tag")
+	flags.StringVar(&options.Cred.URL, "url", os.Getenv("ANCHORECTL_URL"), "anchore url (ANCHORECTL_URL)")
+	flags.StringVar(&options.Cred.UserName, "username", os.Getenv("ANCHORECTL_USERNAME"), "anchore user (ANCHORECTL_USERNAME)")
+	flags.StringVar(&options.Cred.AccountName, "account", os.Getenv("ANCHORECTL_ACCOUNT"), "anchore account (ANCHORECTL_ACCOUNT)")
+	flags.StringVar(&options.AccountUuid, "account-uuid", "", "hub account uuid, to use its credentials of the secrets manager")
+	flags.StringVar(&options.CredentialsSecret, "credentials-secret", "", "name of the credentials in the secrets manager")
+	flags.StringVar(&options.Settings, "settings", "{}", "account analysis settings, as the JSON of the ExecuteRequest metadata")
+	flags.StringVar(&options.Output, "output", "", "file to write the evaluations to instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments %v", flags.Args())
+	}
+	if len(options.SubType) == 0 || len(options.Identifier) == 0 {
+		return nil, errors.New("--subtype and --identifier are required")
+	}
+	if len(options.Tag) == 0 && len(options.Digest) == 0 {
+		return nil, errors.New("--tag or --digest is required")
+	}
+	// the password is never a flag, so it does not end up in the shell history
+	// or the process list
+	options.Cred.Password = os.Getenv("ANCHORECTL_PASSWORD")
+	if len(options.Cred.Password) > 0 && len(options.CredentialsSecret) > 0 {
+		return nil, errors.New("ANCHORECTL_PASSWORD and --credentials-secret are mutually exclusive")
+	}
+	if len(options.Cred.Password) == 0 {
+		// the credentials of the secrets manager are used as a whole, only the
+		// env var defaults of the other flags are dropped silently
+		var overrides []string
+		flags.Visit(func(f *flag.Flag) {
+			if f.Name == "url" || f.Name == "username" || f.Name == "account" {
+				overrides = append(overrides, "--"+f.Name)
+			}
+		})
+		if len(overrides) > 0 {
+			return nil, fmt.Errorf("%s cannot be used with the credentials of the secrets manager", strings.Join(overrides, ", "))
+		}
+		options.Cred = scan.AccountCred{}
+	}
+	return options, nil
+}
+
+// request builds the ExecuteRequest and the asset the hub would send
+func (o *ScanOptions) request() (*service.ExecuteRequest, *domain.Asset, error) {
+	metadata := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(o.Settings), &metadata); err != nil {
+		return nil, nil, fmt.Errorf("invalid --settings: %w", err)
+	}
+	if _, ok := metadata["password"]; ok {
+		return nil, nil, errors.New("--settings cannot carry the password, use ANCHORECTL_PASSWORD")
+	}
+	for key, value := range map[string]string{
+		"url":               o.Cred.URL,
+		"userName":          o.Cred.UserName,
+		"password":          o.Cred.Password,
+		"accountName":       o.Cred.AccountName,
+		"credentialsSecret": o.CredentialsSecret,
+	} {
+		if len(value) > 0 {
+			metadata[key] = value
+		}
+	}
+	metadataBytes, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	attributes, err := json.Marshal(ImageDetails{ImageDigest: o.Digest, ImageTag: o.Tag})
+	if err != nil {
+		return nil, nil, err
+	}
+	req := &service.ExecuteRequest{
+		Account:          &domain.Account{Uuid: o.AccountUuid},
+		AssetType:        AssetType,
+		AssetSubTypes:    []string{o.SubType},
+		AssetIdentifiers: []string{o.Identifier},
+		Metadata:         metadataBytes,
+		TrackingInfo:     []byte("{}"),
+	}
+	asset := &domain.Asset{
+		MasterAsset: &domain.MasterAsset{Type: AssetType, SubType: o.SubType, Identifier: o.Identifier},
+		Profiles:    []*domain.AssetProfile{{Identifier: o.Tag, Type: AssetType, Attributes: attributes}},
+	}
+	return req, asset, nil
+}
+
+// scanAsset runs the analysis of the hub requests on one asset
+func scanAsset(ctx context.Context, req *service.ExecuteRequest, asset *domain.Asset) ([]*domain.Evaluation, error) {
+	runtime := currentSettings()
+	ctx = makeSubLogger(req, ctx, runtime.LogLevel)
+	requestId := utilities.GetRequestId(ctx)
+	defer log.DestroySubLogger(requestId)
+	log.Info(requestId).Msgf("Scanning %s %s", asset.MasterAsset.SubType, asset.MasterAsset.Identifier)
+	return analyseAssets(ctx, requestId, req, runtime, []*domain.Asset{asset})
+}
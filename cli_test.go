@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/testdata"
+	"github.com/stretchr/testify/assert"
+)
+
+var scanArgs = []string{ScanCommand, "--subtype", "aws_ecr_repo", "--identifier", "arn:aws:ecr:us-east-1:1234567:repository/test/plugin-test",
+	"--tag", "v1.0.1", "--url", "testurl", "--username", "test", "--account", "test"}
+
+func TestRunCommand(t *testing.T) {
+	log.Debug().Msg("Inside TestRunCommand - Enter")
+	t.Setenv("ANCHORECTL_PASSWORD", "test")
+	var stdout, stderr bytes.Buffer
+	handled, _ := runCommand(nil, &stdout, &stderr)
+	assert.False(t, handled)
+	handled, _ = runCommand([]string{"serve"}, &stdout, &stderr)
+	assert.False(t, handled)
+
+	handled, code := runCommand([]string{ScanCommand}, &stdout, &stderr)
+	assert.True(t, handled)
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr.String(), "--subtype and --identifier are required")
+
+	stderr.Reset()
+	_, code = runCommand([]string{ScanCommand, "--subtype", "aws_ecr_repo", "--identifier", "arn"}, &stdout, &stderr)
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr.String(), "--tag or --digest is required")
+
+	_, code = runCommand(append(scanArgs, "--settings", "{"), &stdout, &stderr)
+	assert.Equal(t, ExitUsage, code)
+	_, code = runCommand(append(scanArgs, "--password", "test"), &stdout, &stderr)
+	assert.Equal(t, ExitUsage, code)
+	stderr.Reset()
+	_, code = runCommand(append(scanArgs, "--credentials-secret", "prod"), &stdout, &stderr)
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr.String(), "mutually exclusive")
+	_, code = runCommand(append(scanArgs, "--settings", `{"password": "test"}`), &stdout, &stderr)
+	assert.Equal(t, ExitUsage, code)
+	_, code = runCommand([]string{ScanCommand, "--unknown"}, &stdout, &stderr)
+	assert.Equal(t, ExitUsage, code)
+	assert.Empty(t, stdout.String())
+	log.Debug().Msg("Inside TestRunCommand - Exit")
+}
+
+func TestScanCommand(t *testing.T) {
+	log.Debug().Msg("Inside TestScanCommand - Enter")
+	InitConfig()
+	t.Setenv("ANCHORECTL_PASSWORD", "test")
+	testdata.MockGetSystemStatus("testdata/getsystemstatus.json")
+	testdata.MockGetRegistries("testdata/getregistries.json")
+	testdata.MockGetImage("testdata/getimage.json")
+	testdata.MockGetVulnerabilities("testdata/getVulnerabilities.json")
+	scan.IAnchore = testdata.HttpMock1{}
+
+	var stdout, stderr bytes.Buffer
+	code := scanCommand(append(scanArgs[1:], "--settings", `{"evaluationMode": "package", "imageSummary": true}`), &stdout, &stderr)
+	assert.Equal(t, ExitOk, code, stderr.String())
+	var checks []*domain.Evaluation
+	assert.Nil(t, json.Unmarshal(stdout.Bytes(), &checks))
+	assert.NotEmpty(t, checks)
+	summaries := 0
+	for _, check := range checks {
+		if check.Code == SummaryCode {
+			summaries++
+		}
+	}
+	assert.Equal(t, 1, summaries)
+
+	output := filepath.Join(t.TempDir(), "evaluations.json")
+	stdout.Reset()
+	code = scanCommand(append(scanArgs[1:], "--output", output), &stdout, &stderr)
+	assert.Equal(t, ExitOk, code)
+	assert.Empty(t, stdout.String())
+	content, err := os.ReadFile(output)
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(content, &checks))
+	assert.NotEmpty(t, checks)
+
+	testdata.MockGetVulnerabilitiesError()
+	code = scanCommand(scanArgs[1:], &stdout, &stderr)
+	assert.Equal(t, ExitError, code)
+	assert.Contains(t, stderr.String(), "scan failed")
+	log.Debug().Msg("Inside TestScanCommand - Exit")
+}
+
+func TestScanOptionsRequest(t *testing.T) {
+	log.Debug().Msg("Inside TestScanOptionsRequest - Enter")
+	t.Setenv("ANCHORECTL_PASSWORD", "")
+	options, err := parseScanOptions([]string{"--subtype", "dockerhub_repo", "--identifier", "library/alpine", "--digest", "sha256:abc",
+		"--account-uuid", "12245", "--credentials-secret", "prod", "--settings", `{"imageSummary": false}`}, &bytes.Buffer{})
+	assert.Nil(t, err)
+	req, asset, err := options.request()
+	assert.Nil(t, err)
+	assert.Equal(t, "12245", req.Account.Uuid)
+	assert.JSONEq(t, `{"imageSummary": false, "credentialsSecret": "prod"}`, string(req.Metadata))
+	assert.Equal(t, "library/alpine", asset.MasterAsset.Identifier)
+	assert.JSONEq(t, `{"imageDigest": "sha256:abc"}`, string(asset.Profiles[0].Attributes))
+
+	// the secrets manager credentials cannot be overridden by the flags
+	_, err = parseScanOptions([]string{"--subtype", "dockerhub_repo", "--identifier", "library/alpine", "--tag", "3",
+		"--credentials-secret", "prod", "--url", "attacker.example"}, &bytes.Buffer{})
+	assert.NotNil(t, err)
+	log.Debug().Msg("Inside TestScanOptionsRequest - Exit")
+}
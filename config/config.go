@@ -11,33 +11,107 @@ import (
 
 var Config *viper.Viper
 
+// loaded is how Config was read, for its validation
+var loaded *source
+
 func InitConfig() {
-	Config = viper.New()
-	Config.SetEnvPrefix("ch")
-	Config.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	Config.AutomaticEnv()
+	loaded = load()
+	Config = loaded.config
+}
+
+// Reload reads the configuration again from the defaults, env vars, config
+// file and secrets manager, Config is left untouched
+func Reload() (*viper.Viper, error) {
+	reloaded := load()
+	return reloaded.config, reloaded.validate()
+}
 
-	Config.SetDefault("server.address", "127.0.0.1")
-	Config.SetDefault("server.port", 5001)
-	Config.SetDefault("anchorectl.exe", "./anchorectl")
+func load() *source {
+	config := viper.New()
+	config.SetEnvPrefix("ch")
+	config.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	config.AutomaticEnv()
 
-	Config.SetDefault("service.workerpool.size", 3)
-	Config.SetDefault("heartbeat.timer", 45)
+	config.SetDefault("config.file", "")
+	config.SetDefault("server.address", "127.0.0.1")
+	config.SetDefault("server.port", 5001)
+	config.SetDefault("anchorectl.exe", "./anchorectl")
+
+	config.SetDefault("evaluation.mode", "vulnerability")
+	config.SetDefault("evaluation.category.split", false)
+	config.SetDefault("evaluation.summary", false)
+	config.SetDefault("evaluation.upgradeplan", false)
+	config.SetDefault("attribution.enabled", false)
+	config.SetDefault("attribution.separate", false)
+	config.SetDefault("suppression.file", "")
+	config.SetDefault("suppression.action", "flag")
+	config.SetDefault("filter.minseverity", "")
+	config.SetDefault("filter.excludewillnotfix", false)
+	config.SetDefault("filter.excludenofix", false)
+	config.SetDefault("filter.packagescope", "all")
+	config.SetDefault("filter.packagetypes.allow", []string{})
+	config.SetDefault("filter.packagetypes.deny", []string{})
+	config.SetDefault("sla.critical", 15)
+	config.SetDefault("sla.high", 30)
+	config.SetDefault("sla.medium", 90)
+	config.SetDefault("sla.low", 180)
+	config.SetDefault("sla.negligible", 0)
+	config.SetDefault("sla.unknown", 0)
+	config.SetDefault("sla.action", "flag")
+	config.SetDefault("enrichment.epss.file", "")
+	config.SetDefault("enrichment.kev.file", "")
+	config.SetDefault("enrichment.kev.importance", "VERY_HIGH")
+
+	config.SetDefault("server.shutdown.grace", 25)
+	config.SetDefault("server.tls.cert", "")
+	config.SetDefault("server.tls.key", "")
+	config.SetDefault("server.tls.clientca", "")
+	config.SetDefault("metrics.enabled", false)
+	config.SetDefault("metrics.address", ":9102")
+	config.SetDefault("health.interval", 30)
+	config.SetDefault("health.anchore.url", "")
+	config.SetDefault("health.anchore.username", "")
+	config.SetDefault("health.anchore.password", "")
+	config.SetDefault("health.anchore.account", "")
+	config.SetDefault("anchore.retry.count", 8)
+	config.SetDefault("anchore.retry.sleep", 30)
+	config.SetDefault("service.workerpool.size", 3)
+	config.SetDefault("heartbeat.timer", 45)
 
 	// 1GB max. recv size on grpc by default
-	Config.SetDefault("grpc.maxrecvsize", 1024*1024*1024)
+	config.SetDefault("grpc.maxrecvsize", 1024*1024*1024)
 
-	Config.SetDefault("db.log.level", "debug")
-	Config.SetDefault("log.colour", false)
-	Config.SetDefault("log.callerinfo", false)
-	Config.SetDefault("log.level", "debug")
-	Config.SetDefault("log.useconsolewriter", false)
-	Config.SetDefault("log.unixtime", false)
+	config.SetDefault("db.log.level", "debug")
+	config.SetDefault("log.colour", false)
+	config.SetDefault("log.callerinfo", false)
+	config.SetDefault("log.level", "debug")
+	config.SetDefault("log.useconsolewriter", false)
+	config.SetDefault("log.unixtime", false)
 
 	_ = viper.BindEnv("aws.region", "AWS_REGION")          // err will be ignored
-	_ = Config.BindEnv("secret.manager", "SECRET_MANAGER") // err will be ignored
+	_ = config.BindEnv("secret.manager", "SECRET_MANAGER") // err will be ignored
 
-	readSecrets(Config)
+	loaded := readConfigFile(config)
+	readSecrets(config)
+	return loaded
+}
+
+// GetList reads a list which can be given as a comma or space separated env var
+func GetList(key string) []string {
+	return List(Config, key)
+}
+
+// List reads a list of the given configuration, see GetList
+func List(config *viper.Viper, key string) []string {
+	var list []string
+	for _, value := range config.GetStringSlice(key) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
 }
 
 func readSecrets(config *viper.Viper) {
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix is the prefix of the env vars read into the configuration
+const EnvPrefix = "CH_"
+
+// check validates the value of a configuration key
+type check func(value interface{}) error
+
+// checks lists the rules for the configuration values, keys without a rule
+// only need to be known
+var checks = map[string]check{
+	"server.port":               intRange(1, 65535),
+	"server.shutdown.grace":     intRange(0, 3600),
+	"service.workerpool.size":   intRange(1, 1024),
+	"heartbeat.timer":           intRange(1, 3600),
+	"grpc.maxrecvsize":          intRange(1, 2*1024*1024*1024-1),
+	"anchore.retry.count":       intRange(0, 100),
+	"anchore.retry.sleep":       intRange(1, 600),
+	"health.interval":           intRange(1, 3600),
+	"anchorectl.exe":            notEmpty,
+	"evaluation.mode":           oneOf("vulnerability", "package"),
+	"evaluation.category.split": isBool,
+	"evaluation.summary":        isBool,
+	"evaluation.upgradeplan":    isBool,
+	"attribution.enabled":       isBool,
+	"attribution.separate":      isBool,
+	"suppression.action":        oneOf("flag", "drop"),
+	"filter.minseverity":        oneOf("", "unknown", "negligible", "info", "low", "medium", "moderate", "high", "critical", "very_high"),
+	"filter.excludewillnotfix":  isBool,
+	"filter.excludenofix":       isBool,
+	"filter.packagescope":       oneOf("all", "os", "non-os"),
+	"sla.critical":              intRange(0, 3650),
+	"sla.high":                  intRange(0, 3650),
+	"sla.medium":                intRange(0, 3650),
+	"sla.low":                   intRange(0, 3650),
+	"sla.negligible":            intRange(0, 3650),
+	"sla.unknown":               intRange(0, 3650),
+	"sla.action":                oneOf("flag", "escalate"),
+	"enrichment.kev.importance": oneOf("", "low", "medium", "high", "very_high"),
+	"metrics.enabled":           isBool,
+	"log.level":                 oneOf("trace", "debug", "info", "warn", "error", "fatal", "panic", "disabled"),
+	"log.colour":                isBool,
+	"log.callerinfo":            isBool,
+	"log.useconsolewriter":      isBool,
+	"log.unixtime":              isBool,
+}
+
+// source is a loaded configuration with the keys needed to validate it
+type source struct {
+	config *viper.Viper
+	// knownKeys are the keys with a default, captured before the
+	// configuration file is read
+	knownKeys map[string]bool
+	fileKeys  []string
+	fileError error
+}
+
+// readConfigFile merges the YAML or JSON file of config.file into the
+// configuration, env vars still take precedence over its values
+func readConfigFile(config *viper.Viper) *source {
+	loaded := &source{config: config, knownKeys: map[string]bool{}}
+	for _, key := range config.AllKeys() {
+		loaded.knownKeys[key] = true
+	}
+	path := config.GetString("config.file")
+	if len(path) == 0 {
+		return loaded
+	}
+	file := viper.New()
+	file.SetConfigFile(path)
+	if err := file.ReadInConfig(); err != nil {
+		loaded.fileError = fmt.Errorf("could not read config file %s: %w", path, err)
+		return loaded
+	}
+	loaded.fileKeys = file.AllKeys()
+	if err := config.MergeConfigMap(file.AllSettings()); err != nil {
+		loaded.fileError = fmt.Errorf("could not merge config file %s: %w", path, err)
+		return loaded
+	}
+	log.Info().Msgf("Read config file %s", path)
+	return loaded
+}
+
+// Validate checks the configuration file keys and the values of every source,
+// the returned error lists all the problems found
+func Validate() error {
+	return loaded.validate()
+}
+
+func (s *source) validate() error {
+	if s.fileError != nil {
+		return s.fileError
+	}
+	var problems []string
+	for _, key := range s.fileKeys {
+		if !s.knownKeys[key] {
+			problems = append(problems, fmt.Sprintf("%s: unknown key in config file", key))
+		}
+	}
+	for key, check := range checks {
+		if err := check(s.config.Get(key)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	if len(s.config.GetString("server.tls.cert")) > 0 != (len(s.config.GetString("server.tls.key")) > 0) {
+		problems = append(problems, "server.tls: cert and key must be set together")
+	}
+	if len(s.config.GetString("server.tls.clientca")) > 0 && len(s.config.GetString("server.tls.cert")) == 0 {
+		problems = append(problems, "server.tls.clientca: needs server.tls.cert and server.tls.key")
+	}
+	if s.config.GetBool("metrics.enabled") && len(s.config.GetString("metrics.address")) == 0 {
+		problems = append(problems, "metrics.address: must be set when metrics are enabled")
+	}
+	for _, name := range s.unknownEnv() {
+		log.Warn().Msgf("Env var %s does not match any config key, it is ignored", name)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+}
+
+// WatchFile calls onChange whenever the configuration file changes, it does
+// nothing without a configuration file
+func WatchFile(onChange func()) {
+	path := Config.GetString("config.file")
+	if len(path) == 0 {
+		return
+	}
+	file := viper.New()
+	file.SetConfigFile(path)
+	file.OnConfigChange(func(event fsnotify.Event) {
+		log.Info().Msgf("Config file %s changed (%s)", event.Name, event.Op)
+		onChange()
+	})
+	file.WatchConfig()
+}
+
+// unknownEnv lists the env vars with the config prefix which don't match a
+// known key, they are only warned about as the prefix is shared with the
+// other settings of the deployment
+func (s *source) unknownEnv() []string {
+	envNames := map[string]bool{}
+	for key := range s.knownKeys {
+		envNames[EnvPrefix+strings.ToUpper(strings.ReplaceAll(key, ".", "_"))] = true
+	}
+	var unknown []string
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(name, EnvPrefix) && !envNames[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+func intRange(min, max int) check {
+	return func(value interface{}) error {
+		number, err := cast.ToIntE(value)
+		if err != nil {
+			return fmt.Errorf("%v is not a number", value)
+		}
+		if number < min || number > max {
+			return fmt.Errorf("%d is out of range [%d, %d]", number, min, max)
+		}
+		return nil
+	}
+}
+
+func oneOf(values ...string) check {
+	return func(value interface{}) error {
+		text := strings.ToLower(cast.ToString(value))
+		for _, allowed := range values {
+			if text == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", text, strings.Join(values, ", "))
+	}
+}
+
+func isBool(value interface{}) error {
+	if _, err := cast.ToBoolE(value); err != nil {
+		return fmt.Errorf("%v is not a boolean", value)
+	}
+	return nil
+}
+
+func notEmpty(value interface{}) error {
+	if len(cast.ToString(value)) == 0 {
+		return errors.New("must not be empty")
+	}
+	return nil
+}
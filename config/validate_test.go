@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestValidateDefaults(t *testing.T) {
+	log.Debug().Msg("Inside TestValidateDefaults - Enter")
+	InitConfig()
+	assert.Nil(t, Validate())
+	log.Debug().Msg("Inside TestValidateDefaults - Exit")
+}
+
+func TestConfigFile(t *testing.T) {
+	log.Debug().Msg("Inside TestConfigFile - Enter")
+	defer InitConfig()
+	t.Setenv("CH_CONFIG_FILE", writeConfigFile(t, "plugin.yaml", `
+server:
+  port: 6001
+service:
+  workerpool:
+    size: 5
+sla:
+  critical: 7
+  action: escalate
+`))
+	t.Setenv("CH_SLA_CRITICAL", "10")
+	InitConfig()
+	assert.Nil(t, Validate())
+	assert.Equal(t, 6001, Config.GetInt("server.port"))
+	assert.Equal(t, 5, Config.GetInt("service.workerpool.size"))
+	assert.Equal(t, "escalate", Config.GetString("sla.action"))
+	// env vars take precedence over the file
+	assert.Equal(t, 10, Config.GetInt("sla.critical"))
+	// keys missing from the file keep their default
+	assert.Equal(t, 45, Config.GetInt("heartbeat.timer"))
+
+	t.Setenv("CH_CONFIG_FILE", writeConfigFile(t, "plugin.json", `{"evaluation": {"mode": "package"}}`))
+	InitConfig()
+	assert.Nil(t, Validate())
+	assert.Equal(t, "package", Config.GetString("evaluation.mode"))
+	log.Debug().Msg("Inside TestConfigFile - Exit")
+}
+
+func TestValidateErrors(t *testing.T) {
+	log.Debug().Msg("Inside TestValidateErrors - Enter")
+	defer InitConfig()
+	t.Setenv("CH_CONFIG_FILE", writeConfigFile(t, "plugin.yaml", `
+server:
+  port: 0
+  prot: 5001
+service:
+  workerpool:
+    size: -1
+evaluation:
+  mode: cve
+`))
+	t.Setenv("CH_HEARTBEAT_TIMER", "soon")
+	t.Setenv("CH_SERVER_TLS_CERT", "/certs/tls.crt")
+	InitConfig()
+	err := Validate()
+	assert.NotNil(t, err)
+	for _, problem := range []string{
+		"server.prot: unknown key in config file",
+		"server.port: 0 is out of range [1, 65535]",
+		"service.workerpool.size: -1 is out of range [1, 1024]",
+		`evaluation.mode: "cve" is not one of vulnerability, package`,
+		"heartbeat.timer: soon is not a number",
+		"server.tls: cert and key must be set together",
+	} {
+		assert.Contains(t, err.Error(), problem)
+	}
+	log.Debug().Msg("Inside TestValidateErrors - Exit")
+}
+
+func TestValidateMissingFile(t *testing.T) {
+	log.Debug().Msg("Inside TestValidateMissingFile - Enter")
+	defer InitConfig()
+	t.Setenv("CH_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+	InitConfig()
+	assert.ErrorContains(t, Validate(), "could not read config file")
+
+	t.Setenv("CH_CONFIG_FILE", writeConfigFile(t, "plugin.yaml", "server: [port"))
+	InitConfig()
+	assert.ErrorContains(t, Validate(), "could not read config file")
+	log.Debug().Msg("Inside TestValidateMissingFile - Exit")
+}
+
+func TestUnknownEnv(t *testing.T) {
+	log.Debug().Msg("Inside TestUnknownEnv - Enter")
+	t.Setenv("CH_SERVER_PROT", "5001")
+	t.Setenv("CH_SERVER_PORT", "5001")
+	InitConfig()
+	unknown := loaded.unknownEnv()
+	assert.Contains(t, unknown, "CH_SERVER_PROT")
+	assert.NotContains(t, unknown, "CH_SERVER_PORT")
+	assert.Nil(t, Validate())
+	log.Debug().Msg("Inside TestUnknownEnv - Exit")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	service "github.com/cloudbees-compliance/chplugin-go/v0.4.0/servicev0_4_0"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/utilities"
+	"github.com/spf13/viper"
+)
+
+// CredentialsPrefix is the prefix of the secrets manager keys holding the
+// anchore credentials, e.g. anchore.credentials.<account uuid>
+const CredentialsPrefix = "anchore.credentials."
+
+// CredentialsMetadata are the credentials of the ExecuteRequest metadata, the
+// secret reference names credentials of the secrets manager
+type CredentialsMetadata struct {
+	scan.AccountCred
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+// SecretCredentials are the anchore credentials of a secrets manager key. The
+// key of an account uuid is bound to that account, any other key can only be
+// used by the accounts it lists.
+type SecretCredentials struct {
+	scan.AccountCred
+	Accounts []string `json:"accounts,omitempty"`
+}
+
+// allows reports whether the account may use the secret of the name
+func (s SecretCredentials) allows(name string, accountUuid string) bool {
+	if len(accountUuid) == 0 {
+		return false
+	}
+	if strings.EqualFold(name, accountUuid) {
+		return true
+	}
+	for _, account := range s.Accounts {
+		if strings.EqualFold(account, accountUuid) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCredentials reads the anchore credentials of the secrets manager, the
+// value of each key is the JSON of the credentials
+func loadCredentials(requestId string, cfg *viper.Viper) map[string]SecretCredentials {
+	credentials := map[string]SecretCredentials{}
+	for _, key := range cfg.AllKeys() {
+		if !strings.HasPrefix(key, CredentialsPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(key, CredentialsPrefix)
+		var cred SecretCredentials
+		if err := json.Unmarshal([]byte(cfg.GetString(key)), &cred); err != nil {
+			log.Error(requestId).Err(err).Msgf("Error parsing the anchore credentials %s of the secrets manager", name)
+			continue
+		}
+		credentials[name] = cred
+	}
+	return credentials
+}
+
+func makeCredentialMap(req *service.ExecuteRequest, requestId string, runtime *RuntimeSettings) (scan.AccountCred, error) {
+	var metadata CredentialsMetadata
+	if err := json.Unmarshal(req.Metadata, &metadata); err != nil {
+		log.Error(requestId).Err(err).Msgf("Error Parsing Credentials")
+		return scan.AccountCred{}, err
+	}
+	credMap := metadata.AccountCred
+	if len(credMap.Password) > 0 && len(metadata.CredentialsSecret) > 0 {
+		log.Error(requestId).Msg("The request has both a password and a credentials secret")
+		return scan.AccountCred{}, errors.New("password and credentialsSecret are mutually exclusive")
+	}
+	if len(credMap.Password) == 0 {
+		var accountUuid string
+		if req.Account != nil {
+			accountUuid = req.Account.Uuid
+		}
+		name := metadata.CredentialsSecret
+		if len(name) == 0 {
+			name = accountUuid
+		}
+		secret, ok := runtime.Credentials[strings.ToLower(name)]
+		if !ok {
+			log.Error(requestId).Msgf("No anchore credentials in the request or the secrets manager for %s", name)
+			return scan.AccountCred{}, errors.New("no anchore credentials found")
+		}
+		if !secret.allows(name, accountUuid) {
+			log.Error(requestId).Msgf("The anchore credentials %s of the secrets manager are not allowed for account %s", name, accountUuid)
+			return scan.AccountCred{}, errors.New("anchore credentials not allowed for the account")
+		}
+		// the secret is used as a whole, a request could otherwise send its
+		// password to another host or use it on another anchore account
+		if len(credMap.URL) > 0 || len(credMap.UserName) > 0 || len(credMap.AccountName) > 0 {
+			log.Error(requestId).Msgf("The request overrides the url, user or account of the anchore credentials %s", name)
+			return scan.AccountCred{}, errors.New("url, userName and accountName cannot be set with the credentials of the secrets manager")
+		}
+		log.Debug(requestId).Msgf("Using the anchore credentials %s of the secrets manager", name)
+		credMap = secret.AccountCred
+	}
+	credMap.URL = utilities.GetValidURL(credMap.URL)
+	return credMap, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	service "github.com/cloudbees-compliance/chplugin-go/v0.4.0/servicev0_4_0"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func secretsRuntime(t *testing.T) *RuntimeSettings {
+	secrets := viper.New()
+	// the secrets manager values are flat keys
+	assert.Nil(t, secrets.MergeConfigMap(map[string]interface{}{
+		"anchore.credentials.12245":      `{"url":"anchore.example.com","userName":"admin","password":"secret","accountName":"team"}`,
+		"anchore.credentials.Prod":       `{"url":"https://prod.example.com/","userName":"ci","password":"prod-secret","accountName":"prod","accounts":["12245"]}`,
+		"anchore.credentials.67890":      `{"url":"other.example.com","userName":"other","password":"other-secret","accountName":"other"}`,
+		"anchore.credentials.malformed":  `{"url":`,
+		"anchore.credentialsecret.other": `{"password":"ignored"}`,
+	}))
+	return &RuntimeSettings{Credentials: loadCredentials("123", secrets)}
+}
+
+func TestLoadCredentials(t *testing.T) {
+	log.Debug().Msg("Inside TestLoadCredentials - Enter")
+	runtime := secretsRuntime(t)
+	assert.Len(t, runtime.Credentials, 3)
+	assert.Equal(t, scan.AccountCred{URL: "anchore.example.com", UserName: "admin", Password: "secret", AccountName: "team"}, runtime.Credentials["12245"].AccountCred)
+	assert.Equal(t, "prod-secret", runtime.Credentials["prod"].Password)
+	assert.Equal(t, []string{"12245"}, runtime.Credentials["prod"].Accounts)
+	log.Debug().Msg("Inside TestLoadCredentials - Exit")
+}
+
+func TestMakeCredentialMap(t *testing.T) {
+	log.Debug().Msg("Inside TestMakeCredentialMap - Enter")
+	runtime := secretsRuntime(t)
+	account := &domain.Account{Uuid: "12245"}
+
+	// the request credentials are used as they are
+	cred, err := makeCredentialMap(&service.ExecuteRequest{Account: account,
+		Metadata: []byte(`{"url":"testurl","userName":"test","password":"test","accountName":"test"}`)}, "123", runtime)
+	assert.Nil(t, err)
+	assert.Equal(t, scan.AccountCred{URL: "https://testurl", UserName: "test", Password: "test", AccountName: "test"}, cred)
+
+	// the account uuid finds the secret
+	cred, err = makeCredentialMap(&service.ExecuteRequest{Account: account, Metadata: []byte(`{}`)}, "123", runtime)
+	assert.Nil(t, err)
+	assert.Equal(t, scan.AccountCred{URL: "https://anchore.example.com", UserName: "admin", Password: "secret", AccountName: "team"}, cred)
+
+	// the secret reference wins over the account uuid
+	cred, err = makeCredentialMap(&service.ExecuteRequest{Account: account,
+		Metadata: []byte(`{"credentialsSecret":"PROD"}`)}, "123", runtime)
+	assert.Nil(t, err)
+	assert.Equal(t, scan.AccountCred{URL: "https://prod.example.com", UserName: "ci", Password: "prod-secret", AccountName: "prod"}, cred)
+
+	// the request cannot send the secret to another host, or use it as another
+	// user or on another anchore account
+	for _, metadata := range []string{
+		`{"credentialsSecret":"prod","url":"attacker.example"}`,
+		`{"credentialsSecret":"prod","userName":"admin"}`,
+		`{"credentialsSecret":"prod","accountName":"other"}`,
+		`{"url":"attacker.example"}`,
+	} {
+		_, err = makeCredentialMap(&service.ExecuteRequest{Account: account, Metadata: []byte(metadata)}, "123", runtime)
+		assert.NotNil(t, err, metadata)
+	}
+
+	// the secrets of other accounts and the shared ones not listing the
+	// account are refused
+	_, err = makeCredentialMap(&service.ExecuteRequest{Account: account, Metadata: []byte(`{"credentialsSecret":"67890"}`)}, "123", runtime)
+	assert.NotNil(t, err)
+	_, err = makeCredentialMap(&service.ExecuteRequest{Account: &domain.Account{Uuid: "67890"}, Metadata: []byte(`{"credentialsSecret":"prod"}`)}, "123", runtime)
+	assert.NotNil(t, err)
+	_, err = makeCredentialMap(&service.ExecuteRequest{Metadata: []byte(`{"credentialsSecret":"prod"}`)}, "123", runtime)
+	assert.NotNil(t, err)
+
+	// a password and a secret reference are mutually exclusive
+	_, err = makeCredentialMap(&service.ExecuteRequest{Account: account,
+		Metadata: []byte(`{"password":"test","credentialsSecret":"PROD"}`)}, "123", runtime)
+	assert.NotNil(t, err)
+
+	_, err = makeCredentialMap(&service.ExecuteRequest{Account: account, Metadata: []byte(`{"credentialsSecret":"missing"}`)}, "123", runtime)
+	assert.NotNil(t, err)
+	_, err = makeCredentialMap(&service.ExecuteRequest{Account: &domain.Account{Uuid: "unknown"}, Metadata: []byte(`{}`)}, "123", runtime)
+	assert.NotNil(t, err)
+	_, err = makeCredentialMap(&service.ExecuteRequest{Account: account, Metadata: []byte(`{"url"}`)}, "123", runtime)
+	assert.NotNil(t, err)
+	log.Debug().Msg("Inside TestMakeCredentialMap - Exit")
+}
@@ -1,10 +1,43 @@
 package main
 
+import (
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/enrichment"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+)
+
 const CHRequestId = "ch-request-id"
 const AssetType = "BINARY"
 const Summary = "SUMMARY"
 const Detail = "DETAIL"
 const String = "string"
+const NoFix = "None"
+const RemediationCategory = "REMEDIATION"
+const VulnerabilityMode = "vulnerability"
+const PackageMode = "package"
+const VulnerabilityCategory = "VULNERABILITY"
+const OsVulnerabilityCategory = "OS_VULNERABILITY"
+const ApplicationVulnerabilityCategory = "APPLICATION_VULNERABILITY"
+const SuppressedCategory = "SUPPRESSED_VULNERABILITY"
+const BaseImageCategory = "BASE_IMAGE_VULNERABILITY"
+const InheritedOrigin = "inherited from base image"
+const IntroducedOrigin = "introduced by image"
+const SummaryCategory = "IMAGE_SUMMARY"
+const SummaryCode = "VULNERABILITY_SUMMARY"
+const ScanCategory = "VULNERABILITY_SCAN"
+const ScanPassedCode = "VULNERABILITY_SCAN_PASSED"
+const ScanFailedCode = "VULNERABILITY_SCAN_FAILED"
+const ScanPassed = "passed"
+const SlaFlag = "flag"
+const SlaEscalate = "escalate"
+const SuppressionDrop = "drop"
+const SuppressionFlag = "flag"
+const AllPackages = "all"
+const OsPackages = "os"
+const NonOsPackages = "non-os"
+
+var CvssHeaders = []string{"Attack Vector", "Privileges Required", "CVSS v3 Vector", "CVSS v2 Vector"}
+var CvssTypes = []string{String, String, String, String}
+var CvssContexts = []string{Summary, Summary, Detail, Detail}
 
 var SeverityMap = map[string]int{
 	"":          0,
@@ -14,6 +47,32 @@ var SeverityMap = map[string]int{
 	"VERY_HIGH": 4,
 }
 
+// ImportanceOrder lists the compliance hub importances from low to high
+var ImportanceOrder = []string{"LOW", "MEDIUM", "HIGH", "VERY_HIGH"}
+
+// AnchoreSeverityRank orders the anchore severities, the compliance hub
+// importance names are accepted as well
+var AnchoreSeverityRank = map[string]int{
+	"unknown":    0,
+	"negligible": 1,
+	"info":       1,
+	"low":        2,
+	"medium":     3,
+	"moderate":   3,
+	"high":       4,
+	"critical":   5,
+	"very_high":  5,
+}
+
+var OsPackageTypes = map[string]bool{
+	"dpkg": true,
+	"deb":  true,
+	"apk":  true,
+	"apkg": true,
+	"rpm":  true,
+	"kb":   true,
+}
+
 var NexusPorts = []string{
 	":5002",
 	":5003",
@@ -24,3 +83,89 @@ type ImageDetails struct {
 	ImageDigest string `json:"imageDigest,omitempty"`
 	ImageTag    string `json:"imageTag,omitempty"`
 }
+
+// AnalysisSettings holds the per account analysis options, the global
+// defaults come from the config and can be overridden in the request metadata
+type AnalysisSettings struct {
+	EvaluationMode    string         `json:"evaluationMode,omitempty"`
+	SuppressionAction string         `json:"suppressionAction,omitempty"`
+	Suppressions      []Suppression  `json:"suppressions,omitempty"`
+	KevImportance     string         `json:"kevImportance,omitempty"`
+	Filters           FindingFilters `json:"filters,omitempty"`
+	SplitCategories   bool           `json:"splitCategories,omitempty"`
+
+	BaseImageAttribution bool `json:"baseImageAttribution,omitempty"`
+	SeparateBaseImage    bool `json:"separateBaseImage,omitempty"`
+	ImageSummary         bool `json:"imageSummary,omitempty"`
+	UpgradePlans         bool `json:"upgradePlans,omitempty"`
+
+	Sla SlaSettings `json:"sla,omitempty"`
+
+	// Retry is not an account setting, it comes from the runtime settings
+	Retry scan.RetryPolicy `json:"-"`
+	// Feeds are the EPSS and KEV data of the runtime settings snapshot
+	Feeds *enrichment.Feeds `json:"-"`
+}
+
+// SlaSettings are the days allowed to fix a finding per anchore severity, zero
+// days means the severity has no SLA
+type SlaSettings struct {
+	Days   map[string]int `json:"days,omitempty"`
+	Action string         `json:"action,omitempty"`
+}
+
+// FindingFilters select the findings which are sent to the hub
+type FindingFilters struct {
+	MinSeverity       string   `json:"minSeverity,omitempty"`
+	ExcludeWillNotFix bool     `json:"excludeWillNotFix,omitempty"`
+	ExcludeNoFix      bool     `json:"excludeNoFix,omitempty"`
+	PackageScope      string   `json:"packageScope,omitempty"`
+	AllowPackageTypes []string `json:"allowPackageTypes,omitempty"`
+	DenyPackageTypes  []string `json:"denyPackageTypes,omitempty"`
+}
+
+// Suppression is an accepted risk, every field which is set has to match the
+// vulnerability for it to be suppressed
+type Suppression struct {
+	CveId           string `json:"cve,omitempty"`
+	Package         string `json:"package,omitempty"`
+	ImageRepo       string `json:"imageRepo,omitempty"`
+	AssetIdentifier string `json:"assetIdentifier,omitempty"`
+	Owner           string `json:"owner,omitempty"`
+	Justification   string `json:"justification,omitempty"`
+	Expires         string `json:"expires,omitempty"`
+}
+
+type UpgradePlan struct {
+	PackageName    string   `json:"packageName,omitempty"`
+	PackageType    string   `json:"packageType,omitempty"`
+	CurrentVersion string   `json:"currentVersion,omitempty"`
+	TargetVersion  string   `json:"targetVersion,omitempty"`
+	Resolved       []string `json:"resolved,omitempty"`
+	Unresolved     []string `json:"unresolved,omitempty"`
+}
+
+// ScanResult is the passing result of an asset profile whose image has no
+// finding to report
+type ScanResult struct {
+	AssetUuid      string `json:"assetUuid,omitempty"`
+	ProfileUuid    string `json:"profileUuid,omitempty"`
+	AttributesUuid string `json:"attributesUuid,omitempty"`
+	Image          string `json:"image,omitempty"`
+	ImageDigest    string `json:"imageDigest,omitempty"`
+	AnalysedAt     string `json:"analysedAt,omitempty"`
+	Result         string `json:"result"`
+}
+
+type ImageSummary struct {
+	Image         string         `json:"image,omitempty"`
+	ImageDigest   string         `json:"imageDigest,omitempty"`
+	Distro        string         `json:"distro,omitempty"`
+	DistroVersion string         `json:"distroVersion,omitempty"`
+	AnalysedAt    string         `json:"analysedAt,omitempty"`
+	Total         int            `json:"total"`
+	Severities    map[string]int `json:"severities"`
+	Fixable       int            `json:"fixable"`
+	Unfixable     int            `json:"unfixable"`
+	HighestCvss   float64        `json:"highestCvss"`
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strconv"
+
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/enrichment"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+)
+
+// enrichVulnerabilities adds the cvss vector metrics, the EPSS scores and the
+// CISA KEV membership of the feeds to the vulnerabilities, so they are part of
+// the base data of the evaluations
+func enrichVulnerabilities(vulnList *[]scan.VulnerabilityDetail, feeds *enrichment.Feeds) {
+	for i := range *vulnList {
+		v := &(*vulnList)[i]
+		v.CvssV3Vector, v.CvssV2Vector = v.CvssVectors()
+		v.AttackVector = scan.AttackVector(v.CvssV3Vector, v.CvssV2Vector)
+		v.PrivilegesRequired = scan.PrivilegesRequired(v.CvssV3Vector)
+		if score, ok := feeds.GetEpss(v.CveId); ok {
+			v.EpssScore = score.Score
+			v.EpssPercentile = score.Percentile
+		}
+		if entry, ok := feeds.GetKev(v.CveId); ok {
+			v.KnownExploited = true
+			v.KevDateAdded = entry.DateAdded
+			v.KevDueDate = entry.DueDate
+		}
+	}
+}
+
+// addEnrichment adds the detail columns of the loaded enrichment data and
+// escalates the importance of evaluations with known exploited vulnerabilities
+func addEnrichment(reqId string, evalMap map[string]*domain.Evaluation, settings AnalysisSettings) {
+	epssLoaded := settings.Feeds.EpssLoaded()
+	kevLoaded := settings.Feeds.KevLoaded()
+	for _, eval := range evalMap {
+		if epssLoaded {
+			appendDetailColumns(reqId, eval, []string{"EPSS Score", "EPSS Percentile"}, []string{String, String}, []string{Summary, Detail},
+				func(v scan.VulnerabilityDetail) []string {
+					return []string{formatScore(v.EpssScore), formatScore(v.EpssPercentile)}
+				})
+		}
+		if kevLoaded {
+			knownExploited := false
+			appendDetailColumns(reqId, eval, []string{"Known Exploited", "KEV Due Date"}, []string{String, String}, []string{Summary, Detail},
+				func(v scan.VulnerabilityDetail) []string {
+					knownExploited = knownExploited || v.KnownExploited
+					return []string{strconv.FormatBool(v.KnownExploited), v.KevDueDate}
+				})
+			if knownExploited && isNewSevVulnerable(eval.Importance, settings.KevImportance) {
+				eval.Importance = settings.KevImportance
+			}
+		}
+	}
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
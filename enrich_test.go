@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/enrichment"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildEvaluationsEnriched(t *testing.T) {
+	log.Debug().Msg("Inside TestBuildEvaluationsEnriched - Enter")
+	feeds := enrichment.Load("123", enrichment.Files{Epss: "testdata/epss_scores.csv", Kev: "testdata/known_exploited_vulnerabilities.json"}, nil)
+
+	var vulnerabilityList []scan.VulnerabilityDetail
+	vulnerabilitiesByte, _ := os.ReadFile("testdata/getVulnerabilities.json")
+	json.Unmarshal(vulnerabilitiesByte, &vulnerabilityList)
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+
+	evalList, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil,
+		AnalysisSettings{EvaluationMode: VulnerabilityMode, KevImportance: "VERY_HIGH", Feeds: feeds})
+	assert.Nil(t, err)
+	var kevEval *domain.Evaluation
+	for _, eval := range evalList {
+		if eval.Code == "CVE-2022-1304" {
+			kevEval = eval
+		}
+	}
+	assert.NotNil(t, kevEval)
+	assert.Equal(t, "VERY_HIGH", kevEval.Importance)
+	assert.Contains(t, kevEval.DetailHeaders, "EPSS Score")
+	assert.Contains(t, kevEval.DetailHeaders, "Known Exploited")
+	row := kevEval.Failures[0].Details[0].Data
+	assert.Equal(t, len(kevEval.DetailHeaders), len(row))
+	epssColumn := indexOf(kevEval.DetailHeaders, "EPSS Score")
+	assert.Equal(t, []string{"0.00057", "0.22373", "true", "2023-08-22"}, row[epssColumn:epssColumn+4])
+	log.Debug().Msg("Inside TestBuildEvaluationsEnriched - Exit")
+}
@@ -0,0 +1,35 @@
+package enrichment
+
+const EpssFileKey = "enrichment.epss.file"
+const KevFileKey = "enrichment.kev.file"
+const EpssCveColumn = "cve"
+const EpssScoreColumn = "epss"
+const EpssPercentileColumn = "percentile"
+const GzipExtension = ".gz"
+
+// Files are the paths of the EPSS and KEV data files, empty when the data is
+// not configured
+type Files struct {
+	Epss string `json:"epss,omitempty"`
+	Kev  string `json:"kev,omitempty"`
+}
+
+type EpssScore struct {
+	Score      float64 `json:"epss,omitempty"`
+	Percentile float64 `json:"percentile,omitempty"`
+}
+
+type KevCatalog struct {
+	CatalogVersion  string     `json:"catalogVersion,omitempty"`
+	DateReleased    string     `json:"dateReleased,omitempty"`
+	Vulnerabilities []KevEntry `json:"vulnerabilities,omitempty"`
+}
+
+type KevEntry struct {
+	CveId                      string `json:"cveID,omitempty"`
+	VendorProject              string `json:"vendorProject,omitempty"`
+	Product                    string `json:"product,omitempty"`
+	DateAdded                  string `json:"dateAdded,omitempty"`
+	DueDate                    string `json:"dueDate,omitempty"`
+	KnownRansomwareCampaignUse string `json:"knownRansomwareCampaignUse,omitempty"`
+}
@@ -0,0 +1,179 @@
+package enrichment
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+)
+
+// Feeds are one version of the EPSS and KEV data, they are not changed once
+// loaded so a request reads the same data for all of its findings
+type Feeds struct {
+	epss source[EpssScore]
+	kev  source[KevEntry]
+}
+
+// source is the data of one data file and the version of the file it was read from
+type source[T any] struct {
+	fileName string
+	modTime  time.Time
+	data     map[string]T
+}
+
+// Load reads the EPSS and KEV data files, the data of previous is reused for
+// the files which did not change and previous itself is returned when nothing
+// changed. A file which can not be read keeps its previous data.
+func Load(requestId string, files Files, previous *Feeds) *Feeds {
+	var current Feeds
+	if previous != nil {
+		current = *previous
+	}
+	epss, epssChanged := current.epss.load(requestId, files.Epss, parseEpss)
+	kev, kevChanged := current.kev.load(requestId, files.Kev, parseKev)
+	if !epssChanged && !kevChanged {
+		return previous
+	}
+	return &Feeds{epss: epss, kev: kev}
+}
+
+// GetEpss returns the EPSS score and percentile of a CVE
+func (f *Feeds) GetEpss(cveId string) (EpssScore, bool) {
+	if f == nil {
+		return EpssScore{}, false
+	}
+	return f.epss.get(cveId)
+}
+
+// GetKev returns the CISA known exploited vulnerabilities entry of a CVE
+func (f *Feeds) GetKev(cveId string) (KevEntry, bool) {
+	if f == nil {
+		return KevEntry{}, false
+	}
+	return f.kev.get(cveId)
+}
+
+// EpssLoaded reports whether EPSS data is available
+func (f *Feeds) EpssLoaded() bool {
+	return f != nil && f.epss.data != nil
+}
+
+// KevLoaded reports whether the KEV catalog is available
+func (f *Feeds) KevLoaded() bool {
+	return f != nil && f.kev.data != nil
+}
+
+func (s source[T]) load(requestId string, fileName string, parse func(io.Reader) (map[string]T, error)) (source[T], bool) {
+	if len(fileName) == 0 {
+		return source[T]{}, s.fileName != ""
+	}
+	switched := s.fileName != fileName
+	if switched {
+		// the data of another file is not kept
+		s = source[T]{fileName: fileName}
+	}
+	info, err := os.Stat(fileName)
+	if err != nil {
+		log.Error(requestId).Err(err).Msgf("Could not read enrichment data file %s", fileName)
+		return s, switched
+	}
+	if s.data != nil && s.modTime.Equal(info.ModTime()) {
+		return s, false
+	}
+
+	data, err := readFile(fileName, parse)
+	if err != nil {
+		// keep serving the previous data rather than nothing
+		log.Error(requestId).Err(err).Msgf("Error loading enrichment data file %s", fileName)
+		return s, switched
+	}
+	log.Info(requestId).Msgf("Loaded %d entries from enrichment data file %s", len(data), fileName)
+	return source[T]{fileName: fileName, modTime: info.ModTime(), data: data}, true
+}
+
+func readFile[T any](fileName string, parse func(io.Reader) (map[string]T, error)) (map[string]T, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(fileName, GzipExtension) {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+	return parse(reader)
+}
+
+func (s source[T]) get(cveId string) (T, bool) {
+	value, ok := s.data[strings.ToUpper(cveId)]
+	return value, ok
+}
+
+// parseEpss reads the FIRST EPSS csv export, the model version comment line
+// before the header is skipped
+func parseEpss(reader io.Reader) (map[string]EpssScore, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.Comment = '#'
+	csvReader.FieldsPerRecord = -1
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	cveColumn, hasCve := columns[EpssCveColumn]
+	scoreColumn, hasScore := columns[EpssScoreColumn]
+	percentileColumn, hasPercentile := columns[EpssPercentileColumn]
+	if !hasCve || !hasScore || !hasPercentile {
+		return nil, errors.New("epss data file must have cve, epss and percentile columns")
+	}
+
+	scores := map[string]EpssScore{}
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) <= cveColumn || len(record) <= scoreColumn || len(record) <= percentileColumn {
+			continue
+		}
+		score, scoreErr := strconv.ParseFloat(record[scoreColumn], 64)
+		percentile, percentileErr := strconv.ParseFloat(record[percentileColumn], 64)
+		if scoreErr != nil || percentileErr != nil {
+			continue
+		}
+		scores[strings.ToUpper(record[cveColumn])] = EpssScore{Score: score, Percentile: percentile}
+	}
+	return scores, nil
+}
+
+// parseKev reads the CISA known exploited vulnerabilities catalog json feed
+func parseKev(reader io.Reader) (map[string]KevEntry, error) {
+	var catalog KevCatalog
+	if err := json.NewDecoder(reader).Decode(&catalog); err != nil {
+		return nil, err
+	}
+	entries := map[string]KevEntry{}
+	for _, entry := range catalog.Vulnerabilities {
+		entries[strings.ToUpper(entry.CveId)] = entry
+	}
+	return entries, nil
+}
@@ -0,0 +1,67 @@
+package enrichment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEpssFeed(t *testing.T) {
+	log.Debug().Msg("Inside TestEpssFeed - Enter")
+	path, _ := filepath.Abs("../testdata/epss_scores.csv")
+	feeds := Load("123", Files{Epss: path}, nil)
+	assert.True(t, feeds.EpssLoaded())
+	assert.False(t, feeds.KevLoaded())
+	score, ok := feeds.GetEpss("cve-2022-1304")
+	assert.True(t, ok)
+	assert.Equal(t, 0.00057, score.Score)
+	assert.Equal(t, 0.22373, score.Percentile)
+	_, ok = feeds.GetEpss("CVE-2099-0001")
+	assert.False(t, ok)
+
+	assert.False(t, Load("123", Files{}, feeds).EpssLoaded())
+	log.Debug().Msg("Inside TestEpssFeed - Exit")
+}
+
+func TestKevFeed(t *testing.T) {
+	log.Debug().Msg("Inside TestKevFeed - Enter")
+	path, _ := filepath.Abs("../testdata/known_exploited_vulnerabilities.json")
+	feeds := Load("123", Files{Kev: path}, nil)
+	assert.True(t, feeds.KevLoaded())
+	entry, ok := feeds.GetKev("CVE-2022-1304")
+	assert.True(t, ok)
+	assert.Equal(t, "2023-08-22", entry.DueDate)
+
+	var none *Feeds
+	assert.False(t, none.KevLoaded())
+	_, ok = none.GetKev("CVE-2022-1304")
+	assert.False(t, ok)
+	log.Debug().Msg("Inside TestKevFeed - Exit")
+}
+
+func TestFeedReload(t *testing.T) {
+	log.Debug().Msg("Inside TestFeedReload - Enter")
+	fileName := filepath.Join(t.TempDir(), "epss.csv")
+	os.WriteFile(fileName, []byte("cve,epss,percentile\nCVE-2022-1304,0.1,0.5\n"), 0600)
+	first := Load("123", Files{Epss: fileName}, nil)
+	assert.Same(t, first, Load("123", Files{Epss: fileName}, first))
+
+	os.WriteFile(fileName, []byte("cve,epss,percentile\nCVE-2022-1304,0.2,0.6\n"), 0600)
+	os.Chtimes(fileName, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+	second := Load("123", Files{Epss: fileName}, first)
+	score, _ := second.GetEpss("CVE-2022-1304")
+	assert.Equal(t, 0.2, score.Score)
+	// the data already loaded does not change with the file
+	score, _ = first.GetEpss("CVE-2022-1304")
+	assert.Equal(t, 0.1, score.Score)
+
+	// a broken file keeps the previous data
+	os.WriteFile(fileName, []byte("id,score\n"), 0600)
+	os.Chtimes(fileName, time.Now().Add(2*time.Minute), time.Now().Add(2*time.Minute))
+	assert.Same(t, second, Load("123", Files{Epss: fileName}, second))
+	log.Debug().Msg("Inside TestFeedReload - Exit")
+}
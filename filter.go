@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+)
+
+func validateFilters(requestId string, filters FindingFilters) FindingFilters {
+	filters.MinSeverity = strings.ToLower(filters.MinSeverity)
+	if _, ok := AnchoreSeverityRank[filters.MinSeverity]; !ok && len(filters.MinSeverity) > 0 {
+		log.Warn(requestId).Msgf("Minimum severity : %s is not valid, severities are not filtered", filters.MinSeverity)
+		filters.MinSeverity = ""
+	}
+	filters.PackageScope = strings.ToLower(filters.PackageScope)
+	if filters.PackageScope != OsPackages && filters.PackageScope != NonOsPackages && filters.PackageScope != AllPackages {
+		log.Warn(requestId).Msgf("Package scope : %s is defaulting to %s", filters.PackageScope, AllPackages)
+		filters.PackageScope = AllPackages
+	}
+	return filters
+}
+
+// filterVulnerabilities drops the findings the account is not interested in
+func filterVulnerabilities(requestId string, vulnList *[]scan.VulnerabilityDetail, filters FindingFilters) []scan.VulnerabilityDetail {
+	filteredList := []scan.VulnerabilityDetail{}
+	for _, v := range *vulnList {
+		if filters.accepts(v) {
+			filteredList = append(filteredList, v)
+		}
+	}
+	if len(filteredList) != len(*vulnList) {
+		log.Info(requestId).Msgf("Filtered out %d of %d vulnerabilities", len(*vulnList)-len(filteredList), len(*vulnList))
+	}
+	return filteredList
+}
+
+func (f FindingFilters) accepts(v scan.VulnerabilityDetail) bool {
+	if len(f.MinSeverity) > 0 && AnchoreSeverityRank[strings.ToLower(v.Severity)] < AnchoreSeverityRank[f.MinSeverity] {
+		return false
+	}
+	if f.ExcludeWillNotFix && v.WillNotFix {
+		return false
+	}
+	if f.ExcludeNoFix && !hasFix(v) {
+		return false
+	}
+	if f.PackageScope == OsPackages && !isOsPackage(v) {
+		return false
+	}
+	if f.PackageScope == NonOsPackages && isOsPackage(v) {
+		return false
+	}
+	if len(f.AllowPackageTypes) > 0 && !containsFold(f.AllowPackageTypes, v.PackageType) {
+		return false
+	}
+	return !containsFold(f.DenyPackageTypes, v.PackageType)
+}
+
+func isOsPackage(v scan.VulnerabilityDetail) bool {
+	return OsPackageTypes[strings.ToLower(v.PackageType)]
+}
+
+func hasFix(v scan.VulnerabilityDetail) bool {
+	fix := strings.TrimSpace(v.Fix)
+	return len(fix) > 0 && !strings.EqualFold(fix, NoFix)
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterVulnerabilities(t *testing.T) {
+	log.Debug().Msg("Inside TestFilterVulnerabilities - Enter")
+	var vulnerabilityList []scan.VulnerabilityDetail
+	vulnerabilitiesByte, _ := os.ReadFile("testdata/getVulnerabilities.json")
+	json.Unmarshal(vulnerabilitiesByte, &vulnerabilityList)
+
+	all := filterVulnerabilities("123", &vulnerabilityList, validateFilters("123", FindingFilters{}))
+	assert.Equal(t, len(vulnerabilityList), len(all))
+
+	filtered := filterVulnerabilities("123", &vulnerabilityList, validateFilters("123", FindingFilters{MinSeverity: "HIGH"}))
+	assert.Equal(t, 43, len(filtered))
+
+	filtered = filterVulnerabilities("123", &vulnerabilityList, validateFilters("123", FindingFilters{MinSeverity: "low"}))
+	assert.Equal(t, 89, len(filtered))
+
+	filtered = filterVulnerabilities("123", &vulnerabilityList, validateFilters("123", FindingFilters{PackageScope: "non-os"}))
+	assert.Equal(t, 26, len(filtered))
+
+	filtered = filterVulnerabilities("123", &vulnerabilityList, validateFilters("123", FindingFilters{PackageScope: "OS", ExcludeNoFix: true}))
+	for _, v := range filtered {
+		assert.Equal(t, "dpkg", v.PackageType)
+		assert.NotEqual(t, "None", v.Fix)
+	}
+
+	filtered = filterVulnerabilities("123", &vulnerabilityList, validateFilters("123", FindingFilters{ExcludeWillNotFix: true, DenyPackageTypes: []string{"binary"}}))
+	for _, v := range filtered {
+		assert.False(t, v.WillNotFix)
+		assert.NotEqual(t, "binary", v.PackageType)
+	}
+
+	filtered = filterVulnerabilities("123", &vulnerabilityList, validateFilters("123", FindingFilters{AllowPackageTypes: []string{"JAVA"}}))
+	assert.Equal(t, 21, len(filtered))
+	log.Debug().Msg("Inside TestFilterVulnerabilities - Exit")
+}
+
+func TestMakeAnalysisSettingsFilters(t *testing.T) {
+	log.Debug().Msg("Inside TestMakeAnalysisSettingsFilters - Enter")
+	InitConfig()
+	req := mockEcrExecuteRequest()
+	req.Metadata = []byte(`{"url":"testurl","filters":{"minSeverity":"Medium","denyPackageTypes":["binary"]}}`)
+	settings := makeAnalysisSettings(req, "123", currentSettings())
+	assert.Equal(t, "medium", settings.Filters.MinSeverity)
+	assert.Equal(t, AllPackages, settings.Filters.PackageScope)
+	assert.Equal(t, []string{"binary"}, settings.Filters.DenyPackageTypes)
+	log.Debug().Msg("Inside TestMakeAnalysisSettingsFilters - Exit")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	service "github.com/cloudbees-compliance/chplugin-go/v0.4.0/servicev0_4_0"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/config"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const HealthRequestId = "health-check"
+
+// HealthServices are the services reported by the health service, the empty
+// name is the overall health of the plugin
+var HealthServices = []string{"", service.CHPluginService_ServiceDesc.ServiceName}
+
+// healthChecker keeps the grpc health status in line with the anchorectl
+// binary and, when a default anchore is configured, with its system status
+type healthChecker struct {
+	server     *health.Server
+	anchorectl string
+	probe      func(requestId string) error
+	interval   time.Duration
+}
+
+func newHealthChecker() *healthChecker {
+	checker := &healthChecker{
+		server:     health.NewServer(),
+		anchorectl: config.Config.GetString("anchorectl.exe"),
+		interval:   time.Duration(config.Config.GetInt("health.interval")) * time.Second,
+	}
+	cred := scan.AccountCred{
+		URL:         config.Config.GetString("health.anchore.url"),
+		UserName:    config.Config.GetString("health.anchore.username"),
+		Password:    config.Config.GetString("health.anchore.password"),
+		AccountName: config.Config.GetString("health.anchore.account"),
+	}
+	if len(cred.URL) > 0 {
+		checker.probe = func(requestId string) error {
+			return scan.CheckSystemStatus(requestId, cred)
+		}
+	}
+	return checker
+}
+
+// check updates the status of every health service and returns the failure
+func (h *healthChecker) check() error {
+	err := checkExecutable(h.anchorectl)
+	if err == nil && h.probe != nil {
+		if probeErr := h.probe(HealthRequestId); probeErr != nil {
+			err = fmt.Errorf("anchore system status probe failed: %w", probeErr)
+		}
+	}
+	status := healthpb.HealthCheckResponse_SERVING
+	if err != nil {
+		log.Warn().Err(err).Msg("Health check failed, reporting not serving")
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	for _, name := range HealthServices {
+		h.server.SetServingStatus(name, status)
+	}
+	return err
+}
+
+// run checks the health at every interval until the context is cancelled
+func (h *healthChecker) run(ctx context.Context) {
+	h.check()
+	if h.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.check()
+		}
+	}
+}
+
+func checkExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("anchorectl %s not found: %w", path, err)
+	}
+	if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return errors.New("anchorectl " + path + " is not executable")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func servingStatus(t *testing.T, checker *healthChecker) healthpb.HealthCheckResponse_ServingStatus {
+	res, err := checker.server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: HealthServices[1]})
+	assert.Nil(t, err)
+	return res.Status
+}
+
+func TestHealthCheck(t *testing.T) {
+	log.Debug().Msg("Inside TestHealthCheck - Enter")
+	dir := t.TempDir()
+	anchorectl := filepath.Join(dir, "anchorectl")
+	assert.Nil(t, os.WriteFile(anchorectl, []byte("#!/bin/sh\n"), 0755))
+	notExecutable := filepath.Join(dir, "anchorectl.txt")
+	assert.Nil(t, os.WriteFile(notExecutable, []byte("#!/bin/sh\n"), 0644))
+
+	checker := &healthChecker{server: health.NewServer(), anchorectl: anchorectl}
+	assert.Nil(t, checker.check())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, checker))
+
+	checker.anchorectl = notExecutable
+	assert.NotNil(t, checker.check())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, checker))
+
+	checker.anchorectl = filepath.Join(dir, "missing")
+	assert.NotNil(t, checker.check())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, checker))
+
+	checker.anchorectl = dir
+	assert.NotNil(t, checker.check())
+
+	probeErr := errors.New("connection refused")
+	checker.anchorectl = anchorectl
+	checker.probe = func(requestId string) error { return probeErr }
+	assert.ErrorIs(t, checker.check(), probeErr)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, checker))
+
+	checker.probe = func(requestId string) error { return nil }
+	assert.Nil(t, checker.check())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, checker))
+	log.Debug().Msg("Inside TestHealthCheck - Exit")
+}
@@ -1,28 +1,57 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	log "github.com/cloudbees-compliance/chlog-go/log"
 	service "github.com/cloudbees-compliance/chplugin-go/v0.4.0/servicev0_4_0"
 	plugin "github.com/cloudbees-compliance/chplugin-service-go/plugin"
 	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/config"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/metrics"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func main() {
 
 	InitConfig()
+	validateConfig()
+	if handled, code := runCommand(os.Args[1:], os.Stdout, os.Stderr); handled {
+		os.Exit(code)
+	}
+	logBuildInfo()
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	processCtx, cancelProcesses := context.WithCancel(context.Background())
+	scan.SetProcessContext(processCtx)
+
 	netListener := GetNetListener(config.Config.GetString("server.address"), config.Config.GetUint("server.port"))
-	gRPCServer := getGrpcServer(config.Config.GetInt("grpc.maxrecvsize"), config.Config.GetInt("service.workerpool.size"), config.Config.GetInt("heartbeat.timer"))
+	gRPCServer, healthChecker := getGrpcServer(config.Config.GetInt("grpc.maxrecvsize"), config.Config.GetInt("service.workerpool.size"), config.Config.GetInt("heartbeat.timer"))
+	go healthChecker.run(signalCtx)
+	go watchSettings(signalCtx)
+	var metricsServer *http.Server
+	if config.Config.GetBool("metrics.enabled") {
+		metricsServer = metrics.Serve(config.Config.GetString("metrics.address"))
+	}
 
 	// start the server
-	if err := gRPCServer.Serve(netListener); err != nil {
-		log.Panic().Err(err).Msg("failed to serve")
-	}
+	go func() {
+		if err := gRPCServer.Serve(netListener); err != nil {
+			log.Panic().Err(err).Msg("failed to serve")
+		}
+	}()
+	<-signalCtx.Done()
+	shutdown(gRPCServer, healthChecker, cancelProcesses, time.Duration(config.Config.GetInt("server.shutdown.grace"))*time.Second)
+	metrics.Shutdown(metricsServer)
 }
 
 func InitConfig() {
@@ -31,12 +60,19 @@ func InitConfig() {
 	log.Init(config.Config, trackingInfo)
 }
 
-func getGrpcServer(maxrecvSize, workerpoolSize, heartbeatTimer int) *grpc.Server {
-	gRPCServer := grpc.NewServer(
+// validateConfig stops the plugin before it serves with an invalid configuration
+func validateConfig() {
+	if err := config.Validate(); err != nil {
+		log.Panic().Msg(err.Error())
+	}
+}
+
+func getGrpcServer(maxrecvSize, workerpoolSize, heartbeatTimer int) (*grpc.Server, *healthChecker) {
+	gRPCServer := grpc.NewServer(append(tlsServerOptions(),
 		grpc.MaxRecvMsgSize(maxrecvSize),
 		grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
 		grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor()),
-	)
+	)...)
 
 	chPluginService := plugin.CHPluginServiceBuilder(
 		NewAnchoreScanner(),
@@ -44,8 +80,10 @@ func getGrpcServer(maxrecvSize, workerpoolSize, heartbeatTimer int) *grpc.Server
 		int64(heartbeatTimer),
 	)
 	service.RegisterCHPluginServiceServer(gRPCServer, chPluginService)
+	healthChecker := newHealthChecker()
+	healthpb.RegisterHealthServer(gRPCServer, healthChecker.server)
 	log.Info().Msgf("Starting: %s", time.Now().Format(time.RFC3339))
-	return gRPCServer
+	return gRPCServer, healthChecker
 }
 
 func GetNetListener(host string, port uint) net.Listener {
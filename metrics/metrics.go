@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const Namespace = "anchore_plugin"
+
+// operations of the anchore calls
+const (
+	OpGetImage           = "get_image"
+	OpGetVulnerabilities = "get_vulnerabilities"
+	OpGetRegistries      = "get_registries"
+	OpSystemStatus       = "system_status"
+	OpStatusProbe        = "status_probe"
+)
+
+// error types
+const (
+	ErrAnchorectl = "anchorectl"
+	ErrJson       = "json"
+)
+
+// Registry holds the plugin metrics and the go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var AnchoreCallDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: Namespace,
+	Name:      "anchore_call_duration_seconds",
+	Help:      "Duration of the anchorectl calls by operation.",
+	Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+}, []string{"operation"})
+
+var Errors = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: Namespace,
+	Name:      "errors_total",
+	Help:      "Errors by operation and type.",
+}, []string{"operation", "type"})
+
+var AnalysisRetries = factory.NewCounter(prometheus.CounterOpts{
+	Namespace: Namespace,
+	Name:      "analysis_retries_total",
+	Help:      "Waits for an image analysis still pending in anchore.",
+})
+
+var AnalyserDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: Namespace,
+	Name:      "analyser_request_duration_seconds",
+	Help:      "Duration of the ExecuteAnalyser requests by outcome.",
+	Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200},
+}, []string{"outcome"})
+
+var AssetsProcessed = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: Namespace,
+	Name:      "assets_processed_total",
+	Help:      "Asset profiles processed by asset subtype and scan state.",
+}, []string{"subtype", "state"})
+
+var VulnerabilitiesEmitted = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: Namespace,
+	Name:      "vulnerabilities_emitted_total",
+	Help:      "Findings reported to the hub by anchore severity.",
+}, []string{"severity"})
+
+func init() {
+	Registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+// ObserveCall records the duration of an anchore call started at start
+func ObserveCall(operation string, start time.Time) {
+	AnchoreCallDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// CountError counts a failed anchore call
+func CountError(operation, errorType string) {
+	Errors.WithLabelValues(operation, errorType).Inc()
+}
+
+// CountVulnerability counts a finding reported to the hub
+func CountVulnerability(severity string) {
+	severity = strings.ToLower(severity)
+	if len(severity) == 0 {
+		severity = "unknown"
+	}
+	VulnerabilitiesEmitted.WithLabelValues(severity).Inc()
+}
+
+// Handler serves the metrics in the prometheus text format on /metrics
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry}))
+	return mux
+}
+
+// Serve exposes the metrics at the address until the server is shut down
+func Serve(address string) *http.Server {
+	server := &http.Server{Addr: address, Handler: Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		log.Info().Msgf("Serving metrics on %s/metrics", address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("Metrics server failed")
+		}
+	}()
+	return server
+}
+
+// Shutdown stops the metrics server, if it was started
+func Shutdown(server *http.Server) {
+	if server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+}
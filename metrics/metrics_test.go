@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountVulnerability(t *testing.T) {
+	log.Debug().Msg("Inside TestCountVulnerability - Enter")
+	before := testutil.ToFloat64(VulnerabilitiesEmitted.WithLabelValues("critical"))
+	CountVulnerability("Critical")
+	CountVulnerability("critical")
+	CountVulnerability("")
+	assert.Equal(t, before+2, testutil.ToFloat64(VulnerabilitiesEmitted.WithLabelValues("critical")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(VulnerabilitiesEmitted.WithLabelValues("unknown")))
+	log.Debug().Msg("Inside TestCountVulnerability - Exit")
+}
+
+func TestHandler(t *testing.T) {
+	log.Debug().Msg("Inside TestHandler - Enter")
+	ObserveCall(OpGetImage, time.Now().Add(-time.Second))
+	CountError(OpGetImage, ErrJson)
+	AnalysisRetries.Inc()
+
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/metrics")
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, string(body), `anchore_plugin_anchore_call_duration_seconds_count{operation="get_image"} 1`)
+	assert.Contains(t, string(body), `anchore_plugin_errors_total{operation="get_image",type="json"} 1`)
+	assert.Contains(t, string(body), `anchore_plugin_analysis_retries_total 1`)
+	assert.Contains(t, string(body), `go_goroutines`)
+	log.Debug().Msg("Inside TestHandler - Exit")
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"encoding/json"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -11,36 +13,62 @@ import (
 )
 
 func groupResourcesByVulnerability(vulnList *[]scan.VulnerabilityDetail, requestId string) (map[string][]*domain.DetailRow, map[string][]scan.VulnerabilityDetail) {
+	return groupResources(vulnList, requestId, vulnerabilityKey, makeVulnerabilityRow)
+}
+
+func groupResourcesByPackage(vulnList *[]scan.VulnerabilityDetail, requestId string) (map[string][]*domain.DetailRow, map[string][]scan.VulnerabilityDetail) {
+	return groupResources(vulnList, requestId, packageKey, makePackageRow)
+}
+
+func groupResources(vulnList *[]scan.VulnerabilityDetail, requestId string, keyFunc func(scan.VulnerabilityDetail) string,
+	rowFunc func(scan.VulnerabilityDetail, string) []string) (map[string][]*domain.DetailRow, map[string][]scan.VulnerabilityDetail) {
 
 	resourceMap := map[string][]*domain.DetailRow{}
 	baseDataMap := map[string][]scan.VulnerabilityDetail{}
 
 	for _, v := range *vulnList {
-		detail, ok := resourceMap[v.CveId]
-		nvdDataStr := makeJsonString(v.NvdData, requestId, "NvdData")
-		vendorStr := makeJsonString(v.VendorData, requestId, "VendorData")
-		data := []string{v.Package, v.FeedGroup, v.PackageCpe, v.PackageName, v.Url, vendorStr, nvdDataStr, strconv.FormatBool(v.WillNotFix)}
+		key := keyFunc(v)
+		detail, ok := resourceMap[key]
+		data := rowFunc(v, requestId)
 		if !ok {
-			resourceMap[v.CveId] = append([]*domain.DetailRow{}, &domain.DetailRow{Data: data})
+			resourceMap[key] = append([]*domain.DetailRow{}, &domain.DetailRow{Data: data})
 		} else {
-			resourceMap[v.CveId] = append(detail, &domain.DetailRow{Data: data})
+			resourceMap[key] = append(detail, &domain.DetailRow{Data: data})
 		}
-		baseDataList, ok := baseDataMap[v.CveId]
+		baseDataList, ok := baseDataMap[key]
 		if !ok {
-			baseDataMap[v.CveId] = append([]scan.VulnerabilityDetail{}, v)
+			baseDataMap[key] = append([]scan.VulnerabilityDetail{}, v)
 		} else {
-			baseDataMap[v.CveId] = append(baseDataList, v)
+			baseDataMap[key] = append(baseDataList, v)
 		}
 	}
 
 	return resourceMap, baseDataMap
 }
 
+func vulnerabilityKey(v scan.VulnerabilityDetail) string {
+	return v.CveId
+}
+
+func makeVulnerabilityRow(v scan.VulnerabilityDetail, requestId string) []string {
+	nvdDataStr := makeJsonString(v.NvdData, requestId, "NvdData")
+	vendorStr := makeJsonString(v.VendorData, requestId, "VendorData")
+	return []string{v.Package, v.FeedGroup, v.PackageCpe, v.PackageName, v.PackageVersion, v.PackageType, packagePaths(v),
+		v.Url, vendorStr, nvdDataStr, strconv.FormatBool(v.WillNotFix), v.AttackVector, v.PrivilegesRequired, v.CvssV3Vector, v.CvssV2Vector}
+}
+
+func makePackageRow(v scan.VulnerabilityDetail, requestId string) []string {
+	nvdDataStr := makeJsonString(v.NvdData, requestId, "NvdData")
+	vendorStr := makeJsonString(v.VendorData, requestId, "VendorData")
+	return []string{v.CveId, mapSeverity(requestId, v.Severity), v.Fix, v.FeedGroup, packagePaths(v), v.Url, vendorStr, nvdDataStr,
+		strconv.FormatBool(v.WillNotFix), v.AttackVector, v.PrivilegesRequired, v.CvssV3Vector, v.CvssV2Vector}
+}
+
 func mapToEvaluation(reqId string, vulnList *[]scan.VulnerabilityDetail, asset *domain.Asset, ap *domain.AssetProfile, evalMap map[string]*domain.Evaluation) map[string]*domain.Evaluation {
 	resourceMap, baseDataMap := groupResourcesByVulnerability(vulnList, reqId)
 	var eval *domain.Evaluation
 	var ok bool
-	vulnCategory := "VULNERABILITY"
+	vulnCategory := VulnerabilityCategory
 	for _, v := range *vulnList {
 		fixVal := v.Fix
 		if eval, ok = evalMap[v.CveId]; !ok {
@@ -52,13 +80,14 @@ func mapToEvaluation(reqId string, vulnList *[]scan.VulnerabilityDetail, asset *
 				Details:        resourceMap[v.CveId],
 			}
 			eval = &domain.Evaluation{
-				Standard:       "STANDARD",
-				Code:           v.CveId,
-				Name:           v.CveId,
-				Importance:     mapSeverity(reqId, v.Severity),
-				DetailHeaders:  []string{"Package", "Feed Group", "Package CPE", "Package Name", "URL", "Vendor Data", "NVD Data", "Will Not Fix"},
-				DetailTypes:    []string{String, String, String, String, "csv[link]", "json", "json", String},
-				DetailContexts: []string{Summary, Summary, Summary, Detail, Detail, Detail, Detail, Detail},
+				Standard:   "STANDARD",
+				Code:       v.CveId,
+				Name:       v.CveId,
+				Importance: mapSeverity(reqId, v.Severity),
+				DetailHeaders: append([]string{"Package", "Feed Group", "Package CPE", "Package Name", "Package Version", "Package Type", "Package Path",
+					"URL", "Vendor Data", "NVD Data", "Will Not Fix"}, CvssHeaders...),
+				DetailTypes:    append([]string{String, String, String, String, String, String, "csv", "csv[link]", "json", "json", String}, CvssTypes...),
+				DetailContexts: append([]string{Summary, Summary, Summary, Detail, Summary, Summary, Summary, Detail, Detail, Detail, Detail}, CvssContexts...),
 				Category:       &vulnCategory,
 				Failures:       []*domain.AssetResult{ar},
 				BaseData:       getBaseData(baseDataMap[v.CveId]),
@@ -72,6 +101,48 @@ func mapToEvaluation(reqId string, vulnList *[]scan.VulnerabilityDetail, asset *
 	return evalMap
 }
 
+func mapToPackageEvaluation(reqId string, vulnList *[]scan.VulnerabilityDetail, asset *domain.Asset, ap *domain.AssetProfile, evalMap map[string]*domain.Evaluation) map[string]*domain.Evaluation {
+	resourceMap, baseDataMap := groupResourcesByPackage(vulnList, reqId)
+	plans, _ := buildUpgradePlans(reqId, vulnList)
+	planMap := map[string]*UpgradePlan{}
+	for _, plan := range plans {
+		planMap[plan.key()] = plan
+	}
+	var eval *domain.Evaluation
+	var ok bool
+	vulnCategory := VulnerabilityCategory
+	for _, v := range *vulnList {
+		key := packageKey(v)
+		if eval, ok = evalMap[key]; !ok {
+			remediation := planMap[key].remediation()
+			ar := &domain.AssetResult{
+				Asset:          asset.MasterAsset,
+				AssetUuid:      asset.Uuid,
+				AttributesUuid: ap.AttributesUuid,
+				ProfileUuid:    ap.Uuid,
+				Details:        resourceMap[key],
+			}
+			eval = &domain.Evaluation{
+				Standard:       "STANDARD",
+				Code:           packageCode(v.PackageType, packageName(v), v.PackageVersion),
+				Name:           packageName(v) + " " + v.PackageVersion,
+				Importance:     mapSeverity(reqId, v.Severity),
+				DetailHeaders:  append([]string{"Vulnerability", "Severity", "Fix", "Feed Group", "Package Path", "URL", "Vendor Data", "NVD Data", "Will Not Fix"}, CvssHeaders...),
+				DetailTypes:    append([]string{String, String, String, String, "csv", "csv[link]", "json", "json", String}, CvssTypes...),
+				DetailContexts: append([]string{Summary, Summary, Summary, Detail, Summary, Detail, Detail, Detail, Detail}, CvssContexts...),
+				Category:       &vulnCategory,
+				Failures:       []*domain.AssetResult{ar},
+				BaseData:       getBaseData(baseDataMap[key]),
+				Remediation:    &remediation,
+			}
+		} else {
+			updateExistingEval(v, reqId, eval)
+		}
+		evalMap[key] = eval
+	}
+	return evalMap
+}
+
 func mapSeverity(reqId string, severity string) string {
 	lower := strings.ToLower(severity)
 	switch lower {
@@ -89,6 +160,27 @@ func mapSeverity(reqId string, severity string) string {
 	}
 }
 
+// appendDetailColumns adds columns to the evaluation, the values are worked out
+// from the vulnerability behind each detail row
+func appendDetailColumns(reqId string, eval *domain.Evaluation, headers []string, types []string, contexts []string, valueFunc func(v scan.VulnerabilityDetail) []string) {
+	var baseData []scan.VulnerabilityDetail
+	if err := json.Unmarshal(eval.BaseData, &baseData); err != nil {
+		log.Debug(reqId).Msgf("Error occurred while reading the base data of %s", eval.Code)
+	}
+	eval.DetailHeaders = append(eval.DetailHeaders, headers...)
+	eval.DetailTypes = append(eval.DetailTypes, types...)
+	eval.DetailContexts = append(eval.DetailContexts, contexts...)
+	for _, ar := range eval.Failures {
+		for i, row := range ar.Details {
+			if i < len(baseData) {
+				row.Data = append(row.Data, valueFunc(baseData[i])...)
+			} else {
+				row.Data = append(row.Data, make([]string, len(headers))...)
+			}
+		}
+	}
+}
+
 func getBaseData(v []scan.VulnerabilityDetail) []byte {
 	baseDataBytes, err := json.Marshal(v)
 	if err == nil {
@@ -110,10 +202,192 @@ func isNewSevVulnerable(oldSev string, newSev string) bool {
 }
 
 func makeJsonString(v any, requestId string, field string) string {
+	b := makeJsonBytes(v, requestId, field)
+	if b == nil {
+		return ""
+	}
+	return string(b)
+}
+
+func makeJsonBytes(v any, requestId string, field string) []byte {
 	b, err := json.Marshal(v)
 	if err != nil || b == nil {
 		log.Debug(requestId).Msgf("Error occurred while marshalling the field %s", field)
+		return nil
+	}
+	return b
+}
+
+// splitByCategory separates the os package findings from the language package
+// findings, so each half of the image gets its own evaluation category. The
+// findings inherited from the base image can be split off for its owners too.
+func splitByCategory(vulnList []scan.VulnerabilityDetail, settings AnalysisSettings) ([]string, map[string][]scan.VulnerabilityDetail) {
+	categories := []string{VulnerabilityCategory}
+	if settings.SplitCategories {
+		categories = []string{OsVulnerabilityCategory, ApplicationVulnerabilityCategory}
+	}
+	if settings.SeparateBaseImage {
+		categories = append(categories, BaseImageCategory)
+	}
+	categoryMap := map[string][]scan.VulnerabilityDetail{}
+	for _, v := range vulnList {
+		category := VulnerabilityCategory
+		if settings.SeparateBaseImage && v.Origin == InheritedOrigin {
+			category = BaseImageCategory
+		} else if settings.SplitCategories && isOsPackage(v) {
+			category = OsVulnerabilityCategory
+		} else if settings.SplitCategories {
+			category = ApplicationVulnerabilityCategory
+		}
+		categoryMap[category] = append(categoryMap[category], v)
+	}
+	return categories, categoryMap
+}
+
+// setCategory moves the evaluations to the category. The hub keys the
+// evaluations of an asset profile on their code, so outside the default
+// category the code carries the category and a finding reported in several
+// categories keeps one evaluation in each.
+func setCategory(evalMap map[string]*domain.Evaluation, category string) {
+	for _, eval := range evalMap {
+		evalCategory := category
+		eval.Category = &evalCategory
+		eval.Code = categoryCode(eval.Code, category)
+	}
+}
+
+func categoryCode(code string, category string) string {
+	if category == VulnerabilityCategory {
+		return code
+	}
+	return code + "/" + category
+}
+
+// collapseDuplicatePackages merges the findings of a package vendored in
+// several paths, or reported under several aliases, into one finding listing
+// all its paths and aliases, the list must be sorted
+func collapseDuplicatePackages(vulnList []scan.VulnerabilityDetail) []scan.VulnerabilityDetail {
+	collapsed := make([]scan.VulnerabilityDetail, 0, len(vulnList))
+	index := map[string]int{}
+	for _, v := range vulnList {
+		key := findingKey(v)
+		if i, ok := index[key]; ok {
+			if !containsString(collapsed[i].PackagePaths, v.PackagePath) {
+				collapsed[i].PackagePaths = append(collapsed[i].PackagePaths, v.PackagePath)
+			}
+			for _, alias := range v.Aliases {
+				if !containsString(collapsed[i].Aliases, alias) {
+					collapsed[i].Aliases = append(collapsed[i].Aliases, alias)
+				}
+			}
+			continue
+		}
+		if len(v.PackagePath) > 0 {
+			v.PackagePaths = []string{v.PackagePath}
+		}
+		index[key] = len(collapsed)
+		collapsed = append(collapsed, v)
+	}
+	return collapsed
+}
+
+func packagePaths(v scan.VulnerabilityDetail) string {
+	if len(v.PackagePaths) == 0 {
+		return v.PackagePath
+	}
+	return strings.Join(v.PackagePaths, ",")
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sortVulnerabilities orders the vulnerabilities, so the detail rows built from
+// them do not depend on the order anchore lists them in
+func sortVulnerabilities(vulnList []scan.VulnerabilityDetail) {
+	sort.SliceStable(vulnList, func(i, j int) bool {
+		a, b := vulnList[i], vulnList[j]
+		for _, pair := range [][2]string{
+			{a.CveId, b.CveId},
+			{packageName(a), packageName(b)},
+			{a.PackageVersion, b.PackageVersion},
+			{a.PackageType, b.PackageType},
+			{a.PackagePath, b.PackagePath},
+			{a.FeedGroup, b.FeedGroup},
+		} {
+			if pair[0] != pair[1] {
+				return pair[0] < pair[1]
+			}
+		}
+		return false
+	})
+}
+
+// sortEvaluations orders the evaluations by importance, then by the highest cvss
+// score and then by code
+func sortEvaluations(reqId string, evalList []*domain.Evaluation) {
+	scores := map[*domain.Evaluation]float64{}
+	for _, eval := range evalList {
+		scores[eval] = evaluationCvss(reqId, eval)
+	}
+	sort.SliceStable(evalList, func(i, j int) bool {
+		a, b := evalList[i], evalList[j]
+		if SeverityMap[a.Importance] != SeverityMap[b.Importance] {
+			return SeverityMap[a.Importance] > SeverityMap[b.Importance]
+		}
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return getCategory(a) < getCategory(b)
+	})
+}
+
+// evaluationCvss returns the highest cvss score of the vulnerabilities behind
+// the evaluation, evaluations without vulnerability base data score 0
+func evaluationCvss(reqId string, eval *domain.Evaluation) float64 {
+	var baseData []scan.VulnerabilityDetail
+	if err := json.Unmarshal(eval.BaseData, &baseData); err != nil {
+		log.Debug(reqId).Msgf("No vulnerability base data for %s", eval.Code)
+		return 0
+	}
+	highest := 0.0
+	for _, v := range baseData {
+		if score := maxCvss(v); score > highest {
+			highest = score
+		}
+	}
+	return highest
+}
+
+// maxCvss returns the highest cvss v3 score of the vulnerability, the v2 score is
+// used when there is no v3 score
+func maxCvss(v scan.VulnerabilityDetail) float64 {
+	var v3Score, v2Score float64
+	for _, nvd := range v.NvdData {
+		v3Score = math.Max(v3Score, nvd.CvssV3.BaseScore)
+		v2Score = math.Max(v2Score, nvd.CvssV2.BaseScore)
+	}
+	for _, vendor := range v.VendorData {
+		v3Score = math.Max(v3Score, vendor.CvssV3.BaseScore)
+		v2Score = math.Max(v2Score, vendor.CvssV2.BaseScore)
+	}
+	if v3Score > 0 {
+		return v3Score
+	}
+	return v2Score
+}
+
+func getCategory(eval *domain.Evaluation) string {
+	if eval.Category == nil {
 		return ""
 	}
-	return string(b)
+	return *eval.Category
 }
@@ -2,6 +2,8 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
+	"math/rand"
 	"os"
 	"testing"
 
@@ -11,6 +13,79 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+var update = flag.Bool("update", false, "update the golden files")
+
+// goldenEvaluation is the part of an evaluation checked against the golden files
+type goldenEvaluation struct {
+	Code          string     `json:"code"`
+	Name          string     `json:"name"`
+	Importance    string     `json:"importance"`
+	Category      string     `json:"category"`
+	Remediation   string     `json:"remediation"`
+	DetailHeaders []string   `json:"detailHeaders"`
+	Details       [][]string `json:"details"`
+}
+
+func toGolden(evalList []*domain.Evaluation) []byte {
+	var goldenList []goldenEvaluation
+	for _, eval := range evalList {
+		golden := goldenEvaluation{
+			Code:          eval.Code,
+			Name:          eval.Name,
+			Importance:    eval.Importance,
+			Category:      getCategory(eval),
+			DetailHeaders: eval.DetailHeaders,
+		}
+		if eval.Remediation != nil {
+			golden.Remediation = *eval.Remediation
+		}
+		for _, ar := range eval.Failures {
+			for _, row := range ar.Details {
+				golden.Details = append(golden.Details, row.Data)
+			}
+		}
+		goldenList = append(goldenList, golden)
+	}
+	b, _ := json.MarshalIndent(goldenList, "", "  ")
+	return append(b, '\n')
+}
+
+func assertGolden(t *testing.T, fileName string, evalList []*domain.Evaluation) {
+	actual := toGolden(evalList)
+	if *update {
+		os.WriteFile(fileName, actual, 0644)
+	}
+	expected, err := os.ReadFile(fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestBuildEvaluationsGolden(t *testing.T) {
+	log.Debug().Msg("Inside TestBuildEvaluationsGolden - Enter")
+	var vulnerabilityList []scan.VulnerabilityDetail
+	vulnerabilitiesByte, _ := os.ReadFile("testdata/getVulnerabilities.json")
+	json.Unmarshal(vulnerabilitiesByte, &vulnerabilityList)
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+
+	for _, mode := range []string{VulnerabilityMode, PackageMode} {
+		settings := AnalysisSettings{EvaluationMode: mode, UpgradePlans: true, Filters: validateFilters("123", FindingFilters{})}
+		evalList, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil, settings)
+		assert.Nil(t, err)
+		assertGolden(t, "testdata/buildEvaluations."+mode+".golden.json", evalList)
+
+		// the order anchore lists the vulnerabilities in does not change the output
+		shuffledList := append([]scan.VulnerabilityDetail{}, vulnerabilityList...)
+		rand.New(rand.NewSource(1)).Shuffle(len(shuffledList), func(i, j int) {
+			shuffledList[i], shuffledList[j] = shuffledList[j], shuffledList[i]
+		})
+		shuffledEvalList, err := buildEvaluations("123", &shuffledList, asset, assetProfile, "localhost:v1.0.1", nil, settings)
+		assert.Nil(t, err)
+		assert.Equal(t, string(toGolden(evalList)), string(toGolden(shuffledEvalList)))
+	}
+	log.Debug().Msg("Inside TestBuildEvaluationsGolden - Exit")
+}
+
 func TestMapToEvaluation(t *testing.T) {
 	log.Debug().Msg("Inside TestMapToEvaluation - Enter")
 	var vulnerabilityList []scan.VulnerabilityDetail
@@ -25,3 +100,119 @@ func TestMapToEvaluation(t *testing.T) {
 	assert.NotEmpty(t, evaluationMap)
 	log.Debug().Msg("Inside TestMapToEvaluation - Exit")
 }
+
+func TestMapToPackageEvaluation(t *testing.T) {
+	log.Debug().Msg("Inside TestMapToPackageEvaluation - Enter")
+	var vulnerabilityList []scan.VulnerabilityDetail
+	vulnerabilitiesByte, _ := os.ReadFile("testdata/getVulnerabilities.json")
+	json.Unmarshal(vulnerabilitiesByte, &vulnerabilityList)
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+	evaluationMap := mapToPackageEvaluation("123", &vulnerabilityList, asset, assetProfile, map[string]*domain.Evaluation{})
+	assert.NotEmpty(t, evaluationMap)
+
+	eval := evaluationMap[packageKey(scan.VulnerabilityDetail{PackageType: "dpkg", PackageName: "openssl", PackageVersion: "1.1.1n-0+deb11u3"})]
+	assert.NotNil(t, eval)
+	assert.Equal(t, "dpkg:openssl@1.1.1n-0+deb11u3", eval.Code)
+	assert.Equal(t, "HIGH", eval.Importance)
+	assert.Equal(t, 13, len(eval.Failures[0].Details))
+	assert.Equal(t, "Upgrade openssl from 1.1.1n-0+deb11u3 to 1.1.1n-0+deb11u5", *eval.Remediation)
+
+	// the same name and version from two ecosystems keeps two evaluations
+	vulnerabilityList = []scan.VulnerabilityDetail{
+		{CveId: "CVE-2023-32681", Severity: "Medium", PackageName: "requests", PackageVersion: "2.30.0", PackageType: "python", Fix: "2.31.0"},
+		{CveId: "CVE-2023-00001", Severity: "Medium", PackageName: "requests", PackageVersion: "2.30.0", PackageType: "npm", Fix: "2.30.1"},
+	}
+	codes := map[string]bool{}
+	for _, eval := range mapToPackageEvaluation("123", &vulnerabilityList, asset, assetProfile, map[string]*domain.Evaluation{}) {
+		codes[eval.Code] = true
+	}
+	assert.Equal(t, map[string]bool{"python:requests@2.30.0": true, "npm:requests@2.30.0": true}, codes)
+	log.Debug().Msg("Inside TestMapToPackageEvaluation - Exit")
+}
+
+func TestBuildEvaluationsSplitCategories(t *testing.T) {
+	log.Debug().Msg("Inside TestBuildEvaluationsSplitCategories - Enter")
+	var vulnerabilityList []scan.VulnerabilityDetail
+	vulnerabilitiesByte, _ := os.ReadFile("testdata/getVulnerabilities.json")
+	json.Unmarshal(vulnerabilitiesByte, &vulnerabilityList)
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+
+	evalList, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil,
+		AnalysisSettings{EvaluationMode: VulnerabilityMode, SplitCategories: true})
+	assert.Nil(t, err)
+	categoryCount := map[string]int{}
+	for _, eval := range evalList {
+		categoryCount[getCategory(eval)]++
+		if getCategory(eval) == OsVulnerabilityCategory {
+			assert.Equal(t, "debian:11", eval.Failures[0].Details[0].Data[1])
+		}
+	}
+	assert.Equal(t, 0, categoryCount[VulnerabilityCategory])
+	assert.Equal(t, 67, categoryCount[OsVulnerabilityCategory])
+	assert.Equal(t, 26, categoryCount[ApplicationVulnerabilityCategory])
+
+	// a CVE of both an os and a language package keeps one evaluation in each
+	// category
+	vulnerabilityList = []scan.VulnerabilityDetail{
+		{CveId: "CVE-2023-0464", Severity: "High", PackageName: "openssl", PackageVersion: "3.0.2", PackageType: "dpkg", Fix: "None"},
+		{CveId: "CVE-2023-0464", Severity: "High", PackageName: "cryptography", PackageVersion: "39.0.0", PackageType: "python", Fix: "None"},
+	}
+	evalList, err = buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil,
+		AnalysisSettings{EvaluationMode: VulnerabilityMode, SplitCategories: true})
+	assert.Nil(t, err)
+	codes := map[string]string{}
+	for _, eval := range evalList {
+		codes[eval.Code] = getCategory(eval)
+	}
+	assert.Equal(t, len(evalList), len(codes))
+	assert.Equal(t, OsVulnerabilityCategory, codes["CVE-2023-0464/"+OsVulnerabilityCategory])
+	assert.Equal(t, ApplicationVulnerabilityCategory, codes["CVE-2023-0464/"+ApplicationVulnerabilityCategory])
+	log.Debug().Msg("Inside TestBuildEvaluationsSplitCategories - Exit")
+}
+
+func TestCollapseDuplicatePackages(t *testing.T) {
+	log.Debug().Msg("Inside TestCollapseDuplicatePackages - Enter")
+	var vulnerabilityList []scan.VulnerabilityDetail
+	vulnerabilitiesByte, _ := os.ReadFile("testdata/getVulnerabilities.json")
+	json.Unmarshal(vulnerabilitiesByte, &vulnerabilityList)
+	var jar scan.VulnerabilityDetail
+	for _, v := range vulnerabilityList {
+		if v.PackageType == "java" {
+			jar = v
+			break
+		}
+	}
+	vendored := jar
+	vendored.PackagePath = "/opt/lib/" + jar.PackageName + ".jar"
+	duplicated := append(vulnerabilityList, vendored, jar)
+	sortVulnerabilities(duplicated)
+
+	collapsed := collapseDuplicatePackages(duplicated)
+	assert.Equal(t, len(vulnerabilityList), len(collapsed))
+	for _, v := range collapsed {
+		if findingKey(v) == findingKey(jar) {
+			assert.Equal(t, []string{jar.PackagePath, vendored.PackagePath}, v.PackagePaths)
+			assert.Equal(t, jar.PackagePath+","+vendored.PackagePath, packagePaths(v))
+		}
+	}
+
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+	evalList, err := buildEvaluations("123", &duplicated, asset, assetProfile, "localhost:v1.0.1", nil, AnalysisSettings{EvaluationMode: VulnerabilityMode})
+	assert.Nil(t, err)
+	for _, eval := range evalList {
+		if eval.Code != jar.CveId {
+			continue
+		}
+		pathColumn := indexOf(eval.DetailHeaders, "Package Path")
+		paths := []string{}
+		for _, row := range eval.Failures[0].Details {
+			paths = append(paths, row.Data[pathColumn])
+		}
+		assert.Contains(t, paths, jar.PackagePath+","+vendored.PackagePath)
+		assert.NotContains(t, paths, jar.PackagePath)
+	}
+	log.Debug().Msg("Inside TestCollapseDuplicatePackages - Exit")
+}
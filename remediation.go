@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/version"
+)
+
+// buildUpgradePlans works out, for every vulnerable package, the minimum version
+// which fixes all of its vulnerabilities that have a fix
+func buildUpgradePlans(requestId string, vulnList *[]scan.VulnerabilityDetail) ([]*UpgradePlan, map[string][]scan.VulnerabilityDetail) {
+	var plans []*UpgradePlan
+	planMap := map[string]*UpgradePlan{}
+	vulnMap := map[string][]scan.VulnerabilityDetail{}
+
+	for _, v := range *vulnList {
+		key := packageKey(v)
+		plan, ok := planMap[key]
+		if !ok {
+			plan = &UpgradePlan{
+				PackageName:    packageName(v),
+				PackageType:    v.PackageType,
+				CurrentVersion: v.PackageVersion,
+			}
+			planMap[key] = plan
+			plans = append(plans, plan)
+		} else if containsVulnerability(vulnMap[key], v.CveId) {
+			// same vulnerability reported for another path of the package
+			continue
+		}
+		vulnMap[key] = append(vulnMap[key], v)
+
+		fix, found := minimumFix(v)
+		if !found {
+			plan.Unresolved = append(plan.Unresolved, v.CveId)
+			continue
+		}
+		plan.Resolved = append(plan.Resolved, v.CveId)
+		if len(plan.TargetVersion) == 0 || version.Compare(v.PackageType, fix, plan.TargetVersion) > 0 {
+			plan.TargetVersion = fix
+		}
+	}
+	log.Debug(requestId).Msgf("Upgrade plans worked out for %d packages", len(plans))
+	return plans, vulnMap
+}
+
+// minimumFix returns the lowest fix version above the installed version, anchore
+// may list several fix versions when the vulnerability is fixed on many branches
+func minimumFix(v scan.VulnerabilityDetail) (string, bool) {
+	var minimum string
+	for _, candidate := range strings.Split(v.Fix, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if len(candidate) == 0 || strings.EqualFold(candidate, NoFix) {
+			continue
+		}
+		if len(v.PackageVersion) > 0 && version.Compare(v.PackageType, candidate, v.PackageVersion) <= 0 {
+			continue
+		}
+		if len(minimum) == 0 || version.Compare(v.PackageType, candidate, minimum) < 0 {
+			minimum = candidate
+		}
+	}
+	return minimum, len(minimum) > 0
+}
+
+func mapToUpgradePlanEvaluations(reqId string, vulnList *[]scan.VulnerabilityDetail, asset *domain.Asset, ap *domain.AssetProfile) []*domain.Evaluation {
+	var evalList []*domain.Evaluation
+	remediationCategory := RemediationCategory
+	plans, vulnMap := buildUpgradePlans(reqId, vulnList)
+	for _, plan := range plans {
+		if len(plan.TargetVersion) == 0 {
+			continue
+		}
+		importance := ""
+		var details []*domain.DetailRow
+		for _, v := range vulnMap[plan.key()] {
+			severity := mapSeverity(reqId, v.Severity)
+			if isNewSevVulnerable(importance, severity) {
+				importance = severity
+			}
+			_, resolved := minimumFix(v)
+			details = append(details, &domain.DetailRow{Data: []string{v.CveId, severity, v.Fix, strconv.FormatBool(resolved)}})
+		}
+		remediation := plan.remediation()
+		evalList = append(evalList, &domain.Evaluation{
+			Standard:       "STANDARD",
+			Code:           packageCode(plan.PackageType, plan.PackageName, plan.CurrentVersion),
+			Name:           fmt.Sprintf("Upgrade %s to %s", plan.PackageName, plan.TargetVersion),
+			Importance:     importance,
+			DetailHeaders:  []string{"Vulnerability", "Severity", "Fix", "Resolved By Upgrade"},
+			DetailTypes:    []string{String, String, String, String},
+			DetailContexts: []string{Summary, Summary, Detail, Detail},
+			Category:       &remediationCategory,
+			Failures: []*domain.AssetResult{{
+				Asset:          asset.MasterAsset,
+				AssetUuid:      asset.Uuid,
+				AttributesUuid: ap.AttributesUuid,
+				ProfileUuid:    ap.Uuid,
+				Details:        details,
+			}},
+			BaseData:    makeJsonBytes(plan, reqId, "UpgradePlan"),
+			Remediation: &remediation,
+		})
+	}
+	return evalList
+}
+
+// remediation describes the upgrade, packages without any fix fall back to the
+// anchore "None" fix value
+func (p *UpgradePlan) remediation() string {
+	if len(p.TargetVersion) == 0 {
+		return NoFix
+	}
+	return fmt.Sprintf("Upgrade %s from %s to %s", p.PackageName, p.CurrentVersion, p.TargetVersion)
+}
+
+func (p *UpgradePlan) key() string {
+	return p.PackageType + "|" + p.PackageName + "|" + p.CurrentVersion
+}
+
+func packageKey(v scan.VulnerabilityDetail) string {
+	return v.PackageType + "|" + packageName(v) + "|" + v.PackageVersion
+}
+
+// packageCode identifies a package version in the evaluation codes, the type
+// keeps apart packages of the same name and version from other ecosystems
+func packageCode(packageType string, name string, version string) string {
+	if len(packageType) == 0 {
+		return name + "@" + version
+	}
+	return packageType + ":" + name + "@" + version
+}
+
+func packageName(v scan.VulnerabilityDetail) string {
+	if len(v.PackageName) > 0 {
+		return v.PackageName
+	}
+	return v.Package
+}
+
+func containsVulnerability(vulnList []scan.VulnerabilityDetail, cveId string) bool {
+	for _, v := range vulnList {
+		if v.CveId == cveId {
+			return true
+		}
+	}
+	return false
+}
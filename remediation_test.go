@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinimumFix(t *testing.T) {
+	log.Debug().Msg("Inside TestMinimumFix - Enter")
+	fix, found := minimumFix(scan.VulnerabilityDetail{PackageType: "java", PackageVersion: "2.13.4", Fix: "2.12.7.1, 2.13.4.2,2.14.0"})
+	assert.True(t, found)
+	assert.Equal(t, "2.13.4.2", fix)
+
+	_, found = minimumFix(scan.VulnerabilityDetail{PackageType: "dpkg", PackageVersion: "1.46.2-2", Fix: "None"})
+	assert.False(t, found)
+	log.Debug().Msg("Inside TestMinimumFix - Exit")
+}
+
+func TestBuildUpgradePlans(t *testing.T) {
+	log.Debug().Msg("Inside TestBuildUpgradePlans - Enter")
+	vulnList := []scan.VulnerabilityDetail{
+		{CveId: "CVE-2023-0464", PackageName: "libssl1.1", PackageType: "dpkg", PackageVersion: "1.1.1n-0+deb11u3", Fix: "1.1.1n-0+deb11u5", Severity: "High"},
+		{CveId: "CVE-2023-0286", PackageName: "libssl1.1", PackageType: "dpkg", PackageVersion: "1.1.1n-0+deb11u3", Fix: "1.1.1n-0+deb11u4", Severity: "High"},
+		{CveId: "CVE-2023-0286", PackageName: "libssl1.1", PackageType: "dpkg", PackageVersion: "1.1.1n-0+deb11u3", Fix: "1.1.1n-0+deb11u4", Severity: "High"},
+		{CveId: "CVE-2023-2650", PackageName: "libssl1.1", PackageType: "dpkg", PackageVersion: "1.1.1n-0+deb11u3", Fix: "None", Severity: "Medium"},
+	}
+	plans, vulnMap := buildUpgradePlans("123", &vulnList)
+	assert.Equal(t, 1, len(plans))
+	assert.Equal(t, "1.1.1n-0+deb11u5", plans[0].TargetVersion)
+	assert.Equal(t, []string{"CVE-2023-0464", "CVE-2023-0286"}, plans[0].Resolved)
+	assert.Equal(t, []string{"CVE-2023-2650"}, plans[0].Unresolved)
+	assert.Equal(t, 3, len(vulnMap[plans[0].key()]))
+	log.Debug().Msg("Inside TestBuildUpgradePlans - Exit")
+}
+
+func TestMapToUpgradePlanEvaluations(t *testing.T) {
+	log.Debug().Msg("Inside TestMapToUpgradePlanEvaluations - Enter")
+	var vulnerabilityList []scan.VulnerabilityDetail
+	vulnerabilitiesByte, _ := os.ReadFile("testdata/getVulnerabilities.json")
+	json.Unmarshal(vulnerabilitiesByte, &vulnerabilityList)
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+
+	evalList := mapToUpgradePlanEvaluations("123", &vulnerabilityList, asset, assetProfile)
+	assert.NotEmpty(t, evalList)
+	var opensslEval *domain.Evaluation
+	for _, eval := range evalList {
+		assert.Equal(t, RemediationCategory, *eval.Category)
+		assert.NotEmpty(t, eval.Failures[0].Details)
+		if eval.Code == "dpkg:openssl@1.1.1n-0+deb11u3" {
+			opensslEval = eval
+		}
+	}
+	assert.NotNil(t, opensslEval)
+	assert.Equal(t, "Upgrade openssl to 1.1.1n-0+deb11u5", opensslEval.Name)
+	assert.Equal(t, 13, len(opensslEval.Failures[0].Details))
+	log.Debug().Msg("Inside TestMapToUpgradePlanEvaluations - Exit")
+}
+
+func TestBuildEvaluationsUpgradePlans(t *testing.T) {
+	log.Debug().Msg("Inside TestBuildEvaluationsUpgradePlans - Enter")
+	var vulnerabilityList []scan.VulnerabilityDetail
+	vulnerabilitiesByte, _ := os.ReadFile("testdata/getVulnerabilities.json")
+	json.Unmarshal(vulnerabilitiesByte, &vulnerabilityList)
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+
+	plans := func(settings AnalysisSettings) int {
+		evalList, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil, settings)
+		assert.Nil(t, err)
+		count := 0
+		for _, eval := range evalList {
+			if getCategory(eval) == RemediationCategory {
+				count++
+			}
+		}
+		return count
+	}
+	// the upgrade plans are only reported when the account asks for them
+	assert.Zero(t, plans(AnalysisSettings{EvaluationMode: VulnerabilityMode}))
+	assert.Greater(t, plans(AnalysisSettings{EvaluationMode: VulnerabilityMode, UpgradePlans: true}), 0)
+	assert.Zero(t, plans(AnalysisSettings{EvaluationMode: PackageMode, UpgradePlans: true}))
+	log.Debug().Msg("Inside TestBuildEvaluationsUpgradePlans - Exit")
+}
+
+func TestUpgradePlanCodes(t *testing.T) {
+	log.Debug().Msg("Inside TestUpgradePlanCodes - Enter")
+	vulnerabilityList := []scan.VulnerabilityDetail{
+		{CveId: "CVE-2023-32681", Severity: "Medium", PackageName: "requests", PackageVersion: "2.30.0", PackageType: "python", Fix: "2.31.0"},
+		{CveId: "CVE-2023-00001", Severity: "Medium", PackageName: "requests", PackageVersion: "2.30.0", PackageType: "npm", Fix: "2.30.1"},
+	}
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+
+	// the same name and version from two ecosystems keeps two evaluations
+	evalList := mapToUpgradePlanEvaluations("123", &vulnerabilityList, asset, assetProfile)
+	codes := map[string]bool{}
+	for _, eval := range evalList {
+		codes[eval.Code] = true
+	}
+	assert.Equal(t, map[string]bool{"python:requests@2.30.0": true, "npm:requests@2.30.0": true}, codes)
+	assert.Equal(t, "requests@1.0", packageCode("", "requests", "1.0"))
+	log.Debug().Msg("Inside TestUpgradePlanCodes - Exit")
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/config"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/enrichment"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/spf13/viper"
+)
+
+const ReloadRequestId = "config-reload"
+
+// RuntimeSettings are the settings which can change while the plugin runs,
+// each request reads the current snapshot once at its start
+type RuntimeSettings struct {
+	// Analysis are the account defaults, before the metadata overrides
+	Analysis AnalysisSettings
+	// Suppressions are read from the suppression file
+	Suppressions []Suppression
+	// Credentials are the anchore credentials of the secrets manager by
+	// account uuid or secret reference
+	Credentials map[string]SecretCredentials
+	// Enrichment are the EPSS and KEV data files, their data is loaded into
+	// Analysis.Feeds
+	Enrichment enrichment.Files
+	LogLevel   string
+}
+
+// runtimeSettings is swapped as a whole on reload, it is empty until main
+// stores the startup snapshot
+var runtimeSettings atomic.Pointer[RuntimeSettings]
+
+// currentSettings is the snapshot of the last valid configuration, Config is
+// read when no snapshot was stored. When the enrichment data files changed
+// since the snapshot was taken, a snapshot with the new data replaces it.
+func currentSettings() *RuntimeSettings {
+	settings := runtimeSettings.Load()
+	if settings == nil {
+		return loadRuntimeSettings(ReloadRequestId, config.Config)
+	}
+	feeds := enrichment.Load(ReloadRequestId, settings.Enrichment, settings.Analysis.Feeds)
+	if feeds == settings.Analysis.Feeds {
+		return settings
+	}
+	refreshed := *settings
+	refreshed.Analysis.Feeds = feeds
+	runtimeSettings.CompareAndSwap(settings, &refreshed)
+	return &refreshed
+}
+
+func loadRuntimeSettings(requestId string, cfg *viper.Viper) *RuntimeSettings {
+	settings := &RuntimeSettings{
+		Analysis: AnalysisSettings{
+			EvaluationMode:       cfg.GetString("evaluation.mode"),
+			SuppressionAction:    cfg.GetString("suppression.action"),
+			KevImportance:        cfg.GetString("enrichment.kev.importance"),
+			SplitCategories:      cfg.GetBool("evaluation.category.split"),
+			BaseImageAttribution: cfg.GetBool("attribution.enabled"),
+			SeparateBaseImage:    cfg.GetBool("attribution.separate"),
+			ImageSummary:         cfg.GetBool("evaluation.summary"),
+			UpgradePlans:         cfg.GetBool("evaluation.upgradeplan"),
+			Sla: SlaSettings{
+				Days:   map[string]int{},
+				Action: cfg.GetString("sla.action"),
+			},
+			Filters: FindingFilters{
+				MinSeverity:       cfg.GetString("filter.minseverity"),
+				ExcludeWillNotFix: cfg.GetBool("filter.excludewillnotfix"),
+				ExcludeNoFix:      cfg.GetBool("filter.excludenofix"),
+				PackageScope:      cfg.GetString("filter.packagescope"),
+				AllowPackageTypes: config.List(cfg, "filter.packagetypes.allow"),
+				DenyPackageTypes:  config.List(cfg, "filter.packagetypes.deny"),
+			},
+			Retry: scan.RetryPolicy{
+				Count: cfg.GetInt("anchore.retry.count"),
+				Sleep: time.Duration(cfg.GetInt("anchore.retry.sleep")) * time.Second,
+			},
+		},
+		Suppressions: loadSuppressionFile(requestId, cfg.GetString("suppression.file")),
+		Credentials:  loadCredentials(requestId, cfg),
+		Enrichment: enrichment.Files{
+			Epss: cfg.GetString(enrichment.EpssFileKey),
+			Kev:  cfg.GetString(enrichment.KevFileKey),
+		},
+		LogLevel: cfg.GetString("log.level"),
+	}
+	for _, severity := range SlaSeverities {
+		settings.Analysis.Sla.Days[severity] = cfg.GetInt("sla." + severity)
+	}
+	// the data files which did not change are not read again
+	var previous *enrichment.Feeds
+	if current := runtimeSettings.Load(); current != nil {
+		previous = current.Analysis.Feeds
+	}
+	settings.Analysis.Feeds = enrichment.Load(requestId, settings.Enrichment, previous)
+	return settings
+}
+
+// defaults are the account defaults of the snapshot, copied so the account
+// metadata can be unmarshalled into them
+func (r *RuntimeSettings) defaults() AnalysisSettings {
+	settings := r.Analysis
+	settings.Sla.Days = make(map[string]int, len(r.Analysis.Sla.Days))
+	for severity, days := range r.Analysis.Sla.Days {
+		settings.Sla.Days[severity] = days
+	}
+	settings.Filters.AllowPackageTypes = append([]string(nil), r.Analysis.Filters.AllowPackageTypes...)
+	settings.Filters.DenyPackageTypes = append([]string(nil), r.Analysis.Filters.DenyPackageTypes...)
+	settings.Suppressions = nil
+	return settings
+}
+
+// reloadSettings swaps the snapshot for the one of the reloaded configuration,
+// an invalid configuration keeps the current snapshot
+func reloadSettings() {
+	reloaded, err := config.Reload()
+	if err != nil {
+		log.Error().Err(err).Msg("Configuration reload failed, keeping the current settings")
+		return
+	}
+	runtimeSettings.Store(loadRuntimeSettings(ReloadRequestId, reloaded))
+	log.Info().Msg("Configuration reloaded")
+}
+
+// watchSettings reloads the settings when the config file changes or the
+// plugin gets a SIGHUP, until the context is done
+func watchSettings(ctx context.Context) {
+	runtimeSettings.Store(loadRuntimeSettings(ReloadRequestId, config.Config))
+	config.WatchFile(func() {
+		if ctx.Err() == nil {
+			reloadSettings()
+		}
+	})
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	for {
+		select {
+		case <-hangup:
+			log.Info().Msg("SIGHUP received, reloading the configuration")
+			reloadSettings()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
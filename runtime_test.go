@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/config"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/enrichment"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuntimeSettingsDefaults(t *testing.T) {
+	log.Debug().Msg("Inside TestRuntimeSettingsDefaults - Enter")
+	InitConfig()
+	config.Config.Set("filter.packagetypes.allow", "npm,java")
+	defer InitConfig()
+	runtime := loadRuntimeSettings("123", config.Config)
+	assert.Equal(t, 8, runtime.Analysis.Retry.Count)
+	assert.Equal(t, 30*time.Second, runtime.Analysis.Retry.Sleep)
+	assert.Equal(t, "debug", runtime.LogLevel)
+	// the category split is opt-in so the baseline codes and categories stay
+	assert.False(t, runtime.Analysis.SplitCategories)
+	// the base image aware listing is opt-in
+	assert.False(t, runtime.Analysis.BaseImageAttribution)
+	// so are the upgrade plan and image summary evaluations
+	assert.False(t, runtime.Analysis.UpgradePlans)
+	assert.False(t, runtime.Analysis.ImageSummary)
+
+	// the account overrides don't leak into the snapshot
+	settings := runtime.defaults()
+	assert.Nil(t, json.Unmarshal([]byte(`{"sla": {"days": {"critical": 1}}, "filters": {"allowPackageTypes": ["go"]}}`), &settings))
+	assert.Equal(t, 1, settings.Sla.Days["critical"])
+	assert.Equal(t, []string{"go"}, settings.Filters.AllowPackageTypes)
+	assert.Equal(t, 15, runtime.Analysis.Sla.Days["critical"])
+	assert.Equal(t, []string{"npm", "java"}, runtime.Analysis.Filters.AllowPackageTypes)
+	log.Debug().Msg("Inside TestRuntimeSettingsDefaults - Exit")
+}
+
+func TestReloadSettings(t *testing.T) {
+	log.Debug().Msg("Inside TestReloadSettings - Enter")
+	file := filepath.Join(t.TempDir(), "plugin.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte("anchore:\n  retry:\n    count: 2\n"), 0644))
+	t.Setenv("CH_CONFIG_FILE", file)
+	InitConfig()
+	defer func() {
+		runtimeSettings.Store(nil)
+		InitConfig()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchSettings(ctx)
+	assert.Eventually(t, func() bool { return runtimeSettings.Load() != nil }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, currentSettings().Analysis.Retry.Count)
+
+	assert.Nil(t, os.WriteFile(file, []byte("anchore:\n  retry:\n    count: 3\nfilter:\n  minseverity: high\nlog:\n  level: info\n"), 0644))
+	assert.Eventually(t, func() bool { return currentSettings().Analysis.Retry.Count == 3 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "high", currentSettings().Analysis.Filters.MinSeverity)
+	assert.Equal(t, "info", currentSettings().LogLevel)
+
+	// the enrichment files follow the reloaded paths
+	epssFile, err := filepath.Abs("testdata/epss_scores.csv")
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(file, []byte("anchore:\n  retry:\n    count: 4\nenrichment:\n  epss:\n    file: "+epssFile+"\n"), 0644))
+	assert.Eventually(t, func() bool { return currentSettings().Analysis.Retry.Count == 4 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, epssFile, currentSettings().Enrichment.Epss)
+	assert.True(t, currentSettings().Analysis.Feeds.EpssLoaded())
+
+	// an invalid configuration keeps the current settings
+	cancel()
+	assert.Nil(t, os.WriteFile(file, []byte("anchore:\n  retry:\n    count: -1\n"), 0644))
+	reloadSettings()
+	assert.Equal(t, 4, currentSettings().Analysis.Retry.Count)
+	log.Debug().Msg("Inside TestReloadSettings - Exit")
+}
+
+func TestCurrentSettingsFeeds(t *testing.T) {
+	log.Debug().Msg("Inside TestCurrentSettingsFeeds - Enter")
+	InitConfig()
+	fileName := filepath.Join(t.TempDir(), "epss.csv")
+	assert.Nil(t, os.WriteFile(fileName, []byte("cve,epss,percentile\nCVE-2022-1304,0.1,0.5\n"), 0600))
+	config.Config.Set(enrichment.EpssFileKey, fileName)
+	defer func() {
+		runtimeSettings.Store(nil)
+		InitConfig()
+	}()
+	runtimeSettings.Store(loadRuntimeSettings("123", config.Config))
+	started := currentSettings()
+	assert.Same(t, started, currentSettings())
+
+	// a replaced data file gives a new snapshot, the one of a running request keeps its data
+	assert.Nil(t, os.WriteFile(fileName, []byte("cve,epss,percentile\nCVE-2022-1304,0.2,0.6\n"), 0600))
+	assert.Nil(t, os.Chtimes(fileName, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	refreshed := currentSettings()
+	assert.NotSame(t, started, refreshed)
+	assert.Same(t, refreshed, runtimeSettings.Load())
+	score, _ := started.Analysis.Feeds.GetEpss("CVE-2022-1304")
+	assert.Equal(t, 0.1, score.Score)
+	score, _ = refreshed.Analysis.Feeds.GetEpss("CVE-2022-1304")
+	assert.Equal(t, 0.2, score.Score)
+	log.Debug().Msg("Inside TestCurrentSettingsFeeds - Exit")
+}
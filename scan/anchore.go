@@ -1,18 +1,23 @@
 package scan
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/metrics"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type AnchoreScanInterface interface {
-	GetImage(requestId string, imageName string) ([]byte, error)
-	GetVulnerabilities(requestId string, imageName string) ([]byte, error)
-	GetRegistries(requestId string) ([]byte, error)
-	GetSystemStatus(requestId string) ([]byte, error)
+	GetImage(ctx context.Context, requestId string, imageName string) ([]byte, error)
+	GetVulnerabilities(ctx context.Context, requestId string, imageName string, baseImage bool) ([]byte, error)
+	GetRegistries(ctx context.Context, requestId string) ([]byte, error)
+	GetSystemStatus(ctx context.Context, requestId string) ([]byte, error)
 }
 
 type AnchoreWrapper struct {
@@ -25,12 +30,15 @@ func init() {
 	IAnchore = AnchoreWrapper{}
 }
 
-func GetImage(requestId string, imageName string) ([]byte, error) {
+func GetImage(ctx context.Context, requestId string, imageName string) (out []byte, err error) {
+	_, span := tracing.Start(ctx, "anchore.GetImage", attribute.String(tracing.ImageReference, imageName))
+	defer func() { tracing.End(span, err) }()
 	log.Debug(requestId).Msgf("Getting scanned image...")
 
-	out, err := IAnchore.GetImage(requestId, imageName)
+	out, err = IAnchore.GetImage(ctx, requestId, imageName)
 
 	if err != nil {
+		metrics.CountError(metrics.OpGetImage, metrics.ErrAnchorectl)
 		// only output stdout/err if there was a problem
 		if out != nil {
 			log.Error(requestId).Msg(StdErr + string(out))
@@ -40,61 +48,141 @@ func GetImage(requestId string, imageName string) ([]byte, error) {
 	return out, nil
 }
 
-func GetScanStatus(requestId string, imageName string, retryCount int) (*GetAnalysisStatus, bool, error) {
-	status, err := GetImage(requestId, imageName)
+// GetScanStatus gets the analysis status of the image, waiting for a pending
+// analysis as long as the retry policy allows. It reports true only once anchore
+// analyzed the image, the returned status tells apart the other states.
+func GetScanStatus(ctx context.Context, requestId string, imageName string, retry RetryPolicy) (*GetAnalysisStatus, bool, error) {
+	analysisStatus, err := getAnalysisStatus(ctx, requestId, imageName)
 	if err != nil {
 		return nil, false, err
 	}
+	sleep := retry.Sleep
+	for i := 0; i < retry.Count && analysisStatus.State() == StatePending; i++ {
+		log.Debug(requestId).Msgf("status of analysis for attempt %d is - %s", i+1, analysisStatus.AnalysisStatus)
+		log.Debug(requestId).Msgf("sleeping for : %s ", sleep.String())
+		metrics.AnalysisRetries.Inc()
+		if err := waitForAnalysis(ctx, requestId, imageName, i+1, sleep); err != nil {
+			return nil, false, err
+		}
+		analysisStatus, err = getAnalysisStatus(ctx, requestId, imageName)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	switch analysisStatus.State() {
+	case StateAnalyzed:
+		return analysisStatus, true, nil
+	case StatePending:
+		log.Warn(requestId).Msgf("AnchorePlugin: Image %s is still %s after %d attempts", imageName, analysisStatus.AnalysisStatus, retry.Count)
+	case StateFailed:
+		log.Warn(requestId).Msgf("AnchorePlugin: Image %s %s", imageName, analysisStatus.FailureReason())
+	case StateInactive:
+		log.Warn(requestId).Msgf("AnchorePlugin: Image %s is %s", imageName, analysisStatus.ImageStatus)
+	default:
+		log.Warn(requestId).Msgf("AnchorePlugin: Unknown analysis status %s - %s for image %s", analysisStatus.AnalysisStatus, analysisStatus.ImageStatus, imageName)
+	}
+	return analysisStatus, false, nil
+}
+
+func waitForAnalysis(ctx context.Context, requestId string, imageName string, attempt int, sleep time.Duration) (err error) {
+	_, span := tracing.Start(ctx, "anchore.WaitForAnalysis",
+		attribute.String(tracing.ImageReference, imageName), attribute.Int(tracing.RetryAttempt, attempt))
+	defer func() { tracing.End(span, err) }()
+	select {
+	case <-time.After(sleep):
+		return nil
+	case <-ctx.Done():
+		log.Warn(requestId).Msgf("AnchorePlugin: Stopped waiting for the analysis of %s, request is done", imageName)
+		return ctx.Err()
+	case <-processContext.Done():
+		log.Warn(requestId).Msgf("AnchorePlugin: Stopped waiting for the analysis of %s, plugin is shutting down", imageName)
+		return processContext.Err()
+	}
+}
+
+func getAnalysisStatus(ctx context.Context, requestId string, imageName string) (*GetAnalysisStatus, error) {
+	status, err := GetImage(ctx, requestId, imageName)
+	if err != nil {
+		return nil, err
+	}
 	var analysisStatus GetAnalysisStatus
 	jsonerr := json.Unmarshal(status, &analysisStatus)
 	if jsonerr != nil {
 		log.Error().Msgf("AnchorePlugin: Error when marshaling response %s - %s", analysisStatus.AnalysisStatus, analysisStatus.ImageStatus)
-		return nil, false, jsonerr
+		metrics.CountError(metrics.OpGetImage, metrics.ErrJson)
+		return nil, jsonerr
 	}
 	log.Debug(requestId).Msgf("AnchorePlugin: Get image status %s - %s", analysisStatus.AnalysisStatus, analysisStatus.ImageStatus)
-	if strings.Compare("active", analysisStatus.ImageStatus) == 0 &&
-		strings.Compare("analyzed", analysisStatus.AnalysisStatus) == 0 {
-		return &analysisStatus, true, nil
-	} else if strings.Compare("active", analysisStatus.ImageStatus) == 0 &&
-		strings.Compare("analyzed", analysisStatus.AnalysisStatus) != 0 {
-		return getRetryStatus(retryCount, requestId, imageName)
-	} else {
-		return nil, false, nil
+	return &analysisStatus, nil
+}
+
+// State maps the anchore image and analysis status to the state of the scan
+func (s *GetAnalysisStatus) State() string {
+	if !strings.EqualFold(s.ImageStatus, ImageActive) {
+		return StateInactive
+	}
+	switch strings.ToLower(s.AnalysisStatus) {
+	case Analyzed:
+		return StateAnalyzed
+	case NotAnalyzed, Analyzing:
+		return StatePending
+	case AnalysisFailed:
+		return StateFailed
+	default:
+		return StateUnknown
 	}
 }
 
-func getRetryStatus(retryCount int, requestId string, imageName string) (*GetAnalysisStatus, bool, error) {
-	var analysisStatus GetAnalysisStatus
-	var attempts = retryCount
-	sleep := time.Second * SleepDuration
-	log.Debug(requestId).Msgf("Starting Retry for %d times ...", attempts)
-	for i := 0; i < attempts; i++ {
-		status, err := GetImage(requestId, imageName)
-		if err != nil {
-			return nil, false, err
+// FailureReason describes the failed analysis from the status history
+func (s *GetAnalysisStatus) FailureReason() string {
+	for i := len(s.AnalysisStatusDetail) - 1; i >= 0; i-- {
+		detail := s.AnalysisStatusDetail[i]
+		if detail.ToStatus != AnalysisFailed {
+			continue
 		}
-		jsonerr := json.Unmarshal(status, &analysisStatus)
-		if jsonerr != nil {
-			log.Error().Msgf("AnchorePlugin: Error when marshaling response %s - %s", analysisStatus.AnalysisStatus, analysisStatus.ImageStatus)
-			return nil, false, jsonerr
+		reason := fmt.Sprintf("analysis failed at %s while %s", detail.Timestamp, detail.FromStatus)
+		if len(detail.Source.ServiceName) > 0 {
+			reason += fmt.Sprintf(" on %s %s", detail.Source.ServiceName, detail.Source.HostId)
 		}
-		if strings.Compare("active", analysisStatus.ImageStatus) == 0 &&
-			strings.Compare("analyzed", analysisStatus.AnalysisStatus) != 0 {
-			log.Debug(requestId).Msgf("status of analysis for attempt %d is - %s", i+1, analysisStatus.AnalysisStatus)
-			log.Debug(requestId).Msgf("sleeping for : %s ", sleep.String())
-			time.Sleep(sleep)
-			continue
+		return strings.TrimSpace(reason)
+	}
+	return "analysis failed"
+}
+
+// FailedAt is the time the analysis failed, the last update of the image
+// record is used when the status history is missing
+func (s *GetAnalysisStatus) FailedAt() string {
+	for i := len(s.AnalysisStatusDetail) - 1; i >= 0; i-- {
+		if s.AnalysisStatusDetail[i].ToStatus == AnalysisFailed {
+			return s.AnalysisStatusDetail[i].Timestamp
+		}
+	}
+	return s.LastUpdated
+}
+
+// AnalysedAt is the time anchore finished the analysis of the image, the last
+// update of the image record is used when the status history is missing
+func (s *GetAnalysisStatus) AnalysedAt() string {
+	for i := len(s.AnalysisStatusDetail) - 1; i >= 0; i-- {
+		if s.AnalysisStatusDetail[i].ToStatus == Analyzed {
+			return s.AnalysisStatusDetail[i].Timestamp
 		}
-		break
 	}
-	return &analysisStatus, true, nil
+	return s.LastUpdated
 }
 
-func GetVulnerabilities(requestId string, imageName string) ([]VulnerabilityDetail, error) {
+// GetVulnerabilities lists the vulnerabilities of the image, the base image
+// aware listing also tells whether each one is inherited from the base image
+func GetVulnerabilities(ctx context.Context, requestId string, imageName string, baseImage bool) (vulnerabilityList []VulnerabilityDetail, err error) {
+	_, span := tracing.Start(ctx, "anchore.GetVulnerabilities", attribute.String(tracing.ImageReference, imageName))
+	defer func() {
+		span.SetAttributes(attribute.Int(tracing.VulnerabilityCount, len(vulnerabilityList)))
+		tracing.End(span, err)
+	}()
 	log.Debug(requestId).Msgf("Getting vulnerabilities...")
-	var vulnerabilityList []VulnerabilityDetail
-	vulnerabilities, err := IAnchore.GetVulnerabilities(requestId, imageName)
+	vulnerabilities, err := IAnchore.GetVulnerabilities(ctx, requestId, imageName, baseImage)
 	if err != nil {
+		metrics.CountError(metrics.OpGetVulnerabilities, metrics.ErrAnchorectl)
 		// only output stdout/err if there was a problem
 		if vulnerabilities != nil {
 			log.Error(requestId).Msg(StdErr + string(vulnerabilities))
@@ -105,6 +193,7 @@ func GetVulnerabilities(requestId string, imageName string) ([]VulnerabilityDeta
 	jsonerr := json.Unmarshal(vulnerabilities, &vulnerabilityList)
 	if jsonerr != nil {
 		log.Error().Msgf("AnchorePlugin: Error when marshaling response for vulnerabilities")
+		metrics.CountError(metrics.OpGetVulnerabilities, metrics.ErrJson)
 		return nil, jsonerr
 	}
 
@@ -112,21 +201,30 @@ func GetVulnerabilities(requestId string, imageName string) ([]VulnerabilityDeta
 
 }
 
-func GetRegistries(requestId string) (*[]Registry, error) {
+func GetRegistries(ctx context.Context, requestId string) (registries *[]Registry, err error) {
+	_, span := tracing.Start(ctx, "anchore.GetRegistries")
+	defer func() {
+		if registries != nil {
+			span.SetAttributes(attribute.Int(tracing.RegistryCount, len(*registries)))
+		}
+		tracing.End(span, err)
+	}()
 	log.Debug(requestId).Msgf("Getting registries...")
 	var registryList []Registry
-	registries, err := IAnchore.GetRegistries(requestId)
+	out, err := IAnchore.GetRegistries(ctx, requestId)
 	if err != nil {
+		metrics.CountError(metrics.OpGetRegistries, metrics.ErrAnchorectl)
 		// only output stdout/err if there was a problem
-		if registries != nil {
-			log.Error(requestId).Msg(StdErr + string(registries))
+		if out != nil {
+			log.Error(requestId).Msg(StdErr + string(out))
 		}
 		return nil, err
 	}
 
-	jsonerr := json.Unmarshal(registries, &registryList)
+	jsonerr := json.Unmarshal(out, &registryList)
 	if jsonerr != nil {
 		log.Error().Msgf("AnchorePlugin: Error when marshaling response for registries")
+		metrics.CountError(metrics.OpGetRegistries, metrics.ErrJson)
 		return nil, jsonerr
 	}
 
@@ -134,11 +232,14 @@ func GetRegistries(requestId string) (*[]Registry, error) {
 
 }
 
-func GetSystemStatus(requestId string) error {
+// GetSystemStatus checks the anchore of the context credentials is reachable
+// and accepts them
+func GetSystemStatus(ctx context.Context, requestId string) error {
 	log.Debug(requestId).Msgf("Getting system status...")
 
-	sysStatus, err := IAnchore.GetSystemStatus(requestId)
+	sysStatus, err := IAnchore.GetSystemStatus(ctx, requestId)
 	if err != nil {
+		metrics.CountError(metrics.OpSystemStatus, metrics.ErrAnchorectl)
 		if sysStatus != nil {
 			log.Error(requestId).Err(err).Msg(StdErr + string(sysStatus))
 		}
@@ -146,3 +247,14 @@ func GetSystemStatus(requestId string) error {
 	}
 	return nil
 }
+
+// parseAnchorectlVersion reads the Version line of the anchorectl version output
+func parseAnchorectlVersion(out []byte) string {
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if found && strings.TrimSpace(key) == "Version" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -1,10 +1,14 @@
 package scan
 
 import (
+	"context"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/config"
 	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/testdata"
 	"github.com/stretchr/testify/assert"
 )
@@ -15,7 +19,7 @@ func TestGetImage(t *testing.T) {
 	testdata.MockGetImage(path)
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
-	data, err := GetImage("1234", "alpine")
+	data, err := GetImage(context.Background(), "1234", "alpine")
 	assert.Nil(t, err)
 	assert.NotNil(t, data)
 	log.Debug().Msg(" TestGetImage - Exit")
@@ -26,7 +30,7 @@ func TestGetImageErr(t *testing.T) {
 	testdata.MockGetImageError()
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
-	data, err := GetImage("1234", "alpine")
+	data, err := GetImage(context.Background(), "1234", "alpine")
 	assert.NotNil(t, err)
 	assert.Nil(t, data)
 	log.Debug().Msg(" TestGetImageErr - Exit")
@@ -38,7 +42,7 @@ func TestGetRegistries(t *testing.T) {
 	testdata.MockGetRegistries(path)
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
-	data, err := GetRegistries("1234")
+	data, err := GetRegistries(context.Background(), "1234")
 	assert.Nil(t, err)
 	assert.NotNil(t, data)
 	log.Debug().Msg("TestGetRegistries - Exit")
@@ -49,7 +53,7 @@ func TestGetRegistriesErr(t *testing.T) {
 	testdata.MockGetRegistriesError()
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
-	registryList, err := GetRegistries("1234")
+	registryList, err := GetRegistries(context.Background(), "1234")
 	assert.NotNil(t, err)
 	assert.Nil(t, registryList)
 	log.Debug().Msg("TestGetRegistriesErr - Exit")
@@ -60,7 +64,7 @@ func TestGetRegistriesJsonErr(t *testing.T) {
 	testdata.MockGetRegistriesJsonError()
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
-	registryList, err := GetRegistries("1234")
+	registryList, err := GetRegistries(context.Background(), "1234")
 	assert.NotNil(t, err)
 	assert.Nil(t, registryList)
 	log.Debug().Msg("TestGetRegistriesJsonErr - Exit")
@@ -73,7 +77,7 @@ func TestGetVulnerabilities(t *testing.T) {
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
 
-	data, err := GetVulnerabilities("1234", "alpine")
+	data, err := GetVulnerabilities(context.Background(), "1234", "alpine", false)
 	assert.Nil(t, err)
 	assert.NotNil(t, data)
 	log.Debug().Msg(" TestGetVulnerabilities - Exit")
@@ -85,7 +89,7 @@ func TestGetVulnerabilitiesErr(t *testing.T) {
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
 
-	data, err := GetVulnerabilities("1234", "alpine")
+	data, err := GetVulnerabilities(context.Background(), "1234", "alpine", false)
 	assert.Nil(t, data)
 	assert.NotNil(t, err)
 	log.Debug().Msg(" TestGetVulnerabilitiesErr - Exit")
@@ -97,7 +101,7 @@ func TestGetVulnerabilitiesJsonErr(t *testing.T) {
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
 
-	data, err := GetVulnerabilities("1234", "alpine")
+	data, err := GetVulnerabilities(context.Background(), "1234", "alpine", false)
 	assert.Nil(t, data)
 	assert.NotNil(t, err)
 	log.Debug().Msg(" TestGetVulnerabilitiesJsonErr - Exit")
@@ -109,7 +113,7 @@ func TestGetScanStatus(t *testing.T) {
 	testdata.MockGetImage(path)
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
-	status, _, err := GetScanStatus("123", "alpine", 1)
+	status, _, err := GetScanStatus(context.Background(), "123", "alpine", RetryPolicy{Count: 1, Sleep: time.Millisecond})
 	assert.Nil(t, err)
 	assert.Equal(t, status.ImageStatus, "active")
 	log.Debug().Msg("Inside TestGetScanStatus - Exit")
@@ -122,7 +126,7 @@ func TestGetScanStatusErr(t *testing.T) {
 
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
-	status, _, err := GetScanStatus("123", "unittest", 1)
+	status, _, err := GetScanStatus(context.Background(), "123", "unittest", RetryPolicy{Count: 1, Sleep: time.Millisecond})
 
 	assert.Nil(t, err)
 	assert.Equal(t, status.AnalysisStatus, "analyzing")
@@ -134,7 +138,7 @@ func TestGetScanStatusResErr(t *testing.T) {
 	testdata.MockGetImageError()
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
-	status, _, err := GetScanStatus("123", "test", 1)
+	status, _, err := GetScanStatus(context.Background(), "123", "test", RetryPolicy{Count: 1, Sleep: time.Millisecond})
 	assert.NotNil(t, err)
 	assert.Nil(t, status)
 	log.Debug().Msg("Inside TestGetScanStatusResErr - Exit")
@@ -148,7 +152,7 @@ func TestGetScanStatusJsonErr(t *testing.T) {
 
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
-	status, _, err := GetScanStatus("123", "test", 1)
+	status, _, err := GetScanStatus(context.Background(), "123", "test", RetryPolicy{Count: 1, Sleep: time.Millisecond})
 	assert.NotNil(t, err)
 	assert.Nil(t, status)
 
@@ -163,15 +167,86 @@ func TestGetScanStatusInactiveErr(t *testing.T) {
 
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
-	status, isAnalysed, err := GetScanStatus("123", "test", 1)
+	status, isAnalysed, err := GetScanStatus(context.Background(), "123", "test", RetryPolicy{Count: 1, Sleep: time.Millisecond})
 	assert.Nil(t, err)
-	assert.Nil(t, status)
+	assert.Equal(t, "inactive", status.ImageStatus)
+	assert.Equal(t, StateInactive, status.State())
 	assert.Equal(t, false, isAnalysed)
 
 	log.Debug().Msg("Inside TestGetScanStatusInactiveErr - Exit")
 
 }
 
+func TestGetScanStatusFailed(t *testing.T) {
+	log.Debug().Msg("Inside TestGetScanStatusFailed - Enter")
+	path, _ := filepath.Abs("../testdata/getfailedimage.json")
+	testdata.MockGetImage(path)
+
+	mockVar := testdata.HttpMock1{}
+	IAnchore = mockVar
+	status, isAnalysed, err := GetScanStatus(context.Background(), "123", "test", RetryPolicy{Count: RetryCount, Sleep: time.Millisecond})
+	assert.Nil(t, err)
+	assert.False(t, isAnalysed)
+	assert.Equal(t, StateFailed, status.State())
+	assert.Equal(t, "2023-08-25T07:23:08Z", status.FailedAt())
+	assert.Equal(t, "analysis failed at 2023-08-25T07:23:08Z while analyzing on analyzer anchore-anchore-engine-analyzer-75b676f46c-jg5jk",
+		status.FailureReason())
+	log.Debug().Msg("Inside TestGetScanStatusFailed - Exit")
+}
+
+func TestGetScanStatusCancelled(t *testing.T) {
+	log.Debug().Msg("Inside TestGetScanStatusCancelled - Enter")
+	path, _ := filepath.Abs("../testdata/getanalyzingimage.json")
+	testdata.MockGetImage(path)
+	IAnchore = testdata.HttpMock1{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	SetProcessContext(ctx)
+	defer SetProcessContext(context.Background())
+	status, isAnalysed, err := GetScanStatus(context.Background(), "123", "test", DefaultRetryPolicy)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, isAnalysed)
+	assert.Nil(t, status)
+	log.Debug().Msg("Inside TestGetScanStatusCancelled - Exit")
+}
+
+func TestGetScanStatusRequestCancelled(t *testing.T) {
+	log.Debug().Msg("Inside TestGetScanStatusRequestCancelled - Enter")
+	path, _ := filepath.Abs("../testdata/getanalyzingimage.json")
+	testdata.MockGetImage(path)
+	IAnchore = testdata.HttpMock1{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	status, isAnalysed, err := GetScanStatus(ctx, "123", "test", RetryPolicy{Count: 3, Sleep: time.Hour})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, isAnalysed)
+	assert.Nil(t, status)
+	assert.ErrorIs(t, waitForAnalysis(ctx, "123", "test", 1, time.Hour), context.Canceled)
+	log.Debug().Msg("Inside TestGetScanStatusRequestCancelled - Exit")
+}
+
+func TestAnalysisState(t *testing.T) {
+	log.Debug().Msg("Inside TestAnalysisState - Enter")
+	for _, test := range []struct {
+		imageStatus, analysisStatus, state string
+	}{
+		{"active", "analyzed", StateAnalyzed},
+		{"active", "not_analyzed", StatePending},
+		{"active", "analyzing", StatePending},
+		{"active", "analysis_failed", StateFailed},
+		{"active", "queued", StateUnknown},
+		{"inactive", "analyzed", StateInactive},
+		{"deleting", "analyzing", StateInactive},
+	} {
+		status := &GetAnalysisStatus{ImageStatus: test.imageStatus, AnalysisStatus: test.analysisStatus}
+		assert.Equal(t, test.state, status.State(), test.imageStatus+" "+test.analysisStatus)
+	}
+	assert.Equal(t, "analysis failed", (&GetAnalysisStatus{}).FailureReason())
+	log.Debug().Msg("Inside TestAnalysisState - Exit")
+}
+
 func TestGetSystemStatus(t *testing.T) {
 	log.Debug().Msg("Inside TestGetSystemStatus - Enter")
 	path, _ := filepath.Abs("../testdata/getsystemstatus.json")
@@ -179,7 +254,7 @@ func TestGetSystemStatus(t *testing.T) {
 
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
-	err := GetSystemStatus("123")
+	err := GetSystemStatus(context.Background(), "123")
 	assert.Nil(t, err)
 	log.Debug().Msg("Inside TestGetSystemStatus - Exit")
 }
@@ -190,7 +265,51 @@ func TestGetSystemStatusErr(t *testing.T) {
 
 	mockVar := testdata.HttpMock1{}
 	IAnchore = mockVar
-	err := GetSystemStatus("123")
+	err := GetSystemStatus(context.Background(), "123")
 	assert.NotNil(t, err)
 	log.Debug().Msg("Inside TestGetSystemStatusErr - Exit")
 }
+
+func TestParseAnchorectlVersion(t *testing.T) {
+	log.Debug().Msg("Inside TestParseAnchorectlVersion - Enter")
+	out := "Application:        anchorectl\nVersion:            1.8.0\nSyftVersion:        v0.90.0\nBuildDate:          2023-09-22T16:17:42Z\n"
+	assert.Equal(t, "1.8.0", parseAnchorectlVersion([]byte(out)))
+	assert.Equal(t, "", parseAnchorectlVersion([]byte("command not found")))
+	log.Debug().Msg("Inside TestParseAnchorectlVersion - Exit")
+}
+
+func TestGetAnchorectlVersion(t *testing.T) {
+	log.Debug().Msg("Inside TestGetAnchorectlVersion - Enter")
+	config.InitConfig()
+	defer config.InitConfig()
+	anchorectl := filepath.Join(t.TempDir(), "anchorectl")
+	config.Config.Set("anchorectl.exe", anchorectl)
+
+	assert.Nil(t, os.WriteFile(anchorectl, []byte("#!/bin/sh\necho 'Application: anchorectl'\necho 'Version:     1.8.0'\n"), 0755))
+	version, err := GetAnchorectlVersion("123")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.8.0", version)
+
+	assert.Nil(t, os.WriteFile(anchorectl, []byte("#!/bin/sh\necho 'unknown command'\n"), 0755))
+	_, err = GetAnchorectlVersion("123")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, os.WriteFile(anchorectl, []byte("#!/bin/sh\nexit 1\n"), 0755))
+	_, err = GetAnchorectlVersion("123")
+	assert.NotNil(t, err)
+	log.Debug().Msg("Inside TestGetAnchorectlVersion - Exit")
+}
+
+func TestAnchorectlEnv(t *testing.T) {
+	log.Debug().Msg("Inside TestAnchorectlEnv - Enter")
+	cred := AccountCred{URL: "https://anchore.example.com", UserName: "ci", Password: "secret", AccountName: "team"}
+	ctx := WithCredentials(context.Background(), cred)
+	assert.Equal(t, cred, CredentialsFrom(ctx))
+	assert.Equal(t, AccountCred{}, CredentialsFrom(context.Background()))
+
+	env := anchorectlEnv(CredentialsFrom(ctx))
+	assert.Contains(t, env, "ANCHORECTL_URL=https://anchore.example.com")
+	assert.Contains(t, env, "ANCHORECTL_PASSWORD=secret")
+	assert.Contains(t, env, "ANCHORECTL_ACCOUNT=team")
+	log.Debug().Msg("Inside TestAnchorectlEnv - Exit")
+}
@@ -0,0 +1,123 @@
+package scan
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+var attackVectors = map[string]string{
+	"N": "NETWORK",
+	"A": "ADJACENT_NETWORK",
+	"L": "LOCAL",
+	"P": "PHYSICAL",
+}
+
+var privilegesRequired = map[string]string{
+	"N": "NONE",
+	"L": "LOW",
+	"H": "HIGH",
+}
+
+// cvssMetrics is the anchore api layout of a cvss score, anchorectl flattens
+// the base metrics into the score itself
+type cvssMetrics struct {
+	BaseMetrics struct {
+		BaseScore           *float64 `json:"base_score"`
+		ExploitabilityScore *float64 `json:"exploitability_score"`
+		ImpactScore         *float64 `json:"impact_score"`
+	} `json:"base_metrics"`
+	VectorString string `json:"vector_string"`
+}
+
+func (c *CvsScore) UnmarshalJSON(data []byte) error {
+	type cvsScore CvsScore
+	var score cvsScore
+	if err := json.Unmarshal(data, &score); err != nil {
+		return err
+	}
+	var metrics cvssMetrics
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return err
+	}
+	if metrics.BaseMetrics.BaseScore != nil {
+		score.BaseScore = *metrics.BaseMetrics.BaseScore
+	}
+	if metrics.BaseMetrics.ExploitabilityScore != nil {
+		score.ExploitabilityScore = *metrics.BaseMetrics.ExploitabilityScore
+	}
+	if metrics.BaseMetrics.ImpactScore != nil {
+		score.ImpactScore = *metrics.BaseMetrics.ImpactScore
+	}
+	if len(score.VectorString) == 0 {
+		score.VectorString = metrics.VectorString
+	}
+	*c = CvsScore(score)
+	return nil
+}
+
+func (v *VendorData) UnmarshalJSON(data []byte) error {
+	type vendorData VendorData
+	var vendor struct {
+		vendorData
+		CvssV2Snake *CvsScore `json:"cvss_v2"`
+		CvssV3Snake *CvsScore `json:"cvss_v3"`
+	}
+	if err := json.Unmarshal(data, &vendor); err != nil {
+		return err
+	}
+	if vendor.CvssV2Snake != nil {
+		vendor.CvssV2 = *vendor.CvssV2Snake
+	}
+	if vendor.CvssV3Snake != nil {
+		vendor.CvssV3 = *vendor.CvssV3Snake
+	}
+	*v = VendorData(vendor.vendorData)
+	return nil
+}
+
+// CvssVectors returns the cvss v3 and v2 vectors of the vulnerability, the
+// vendor vectors are preferred over the nvd ones
+func (v VulnerabilityDetail) CvssVectors() (string, string) {
+	var v3Vector, v2Vector string
+	for _, vendor := range v.VendorData {
+		if len(v3Vector) == 0 {
+			v3Vector = vendor.CvssV3.VectorString
+		}
+		if len(v2Vector) == 0 {
+			v2Vector = vendor.CvssV2.VectorString
+		}
+	}
+	for _, nvd := range v.NvdData {
+		if len(v3Vector) == 0 {
+			v3Vector = nvd.CvssV3.VectorString
+		}
+		if len(v2Vector) == 0 {
+			v2Vector = nvd.CvssV2.VectorString
+		}
+	}
+	return v3Vector, v2Vector
+}
+
+// ParseCvssVector splits a cvss vector like CVSS:3.1/AV:N/AC:L/PR:N into its metrics
+func ParseCvssVector(vector string) map[string]string {
+	metrics := map[string]string{}
+	for _, part := range strings.Split(vector, "/") {
+		if key, value, ok := strings.Cut(part, ":"); ok {
+			metrics[key] = value
+		}
+	}
+	return metrics
+}
+
+// AttackVector returns the attack vector of the v3 vector, falling back to the v2 vector
+func AttackVector(v3Vector string, v2Vector string) string {
+	if av, ok := attackVectors[ParseCvssVector(v3Vector)["AV"]]; ok {
+		return av
+	}
+	return attackVectors[ParseCvssVector(v2Vector)["AV"]]
+}
+
+// PrivilegesRequired returns the privileges required of the v3 vector, v2 vectors do not have it
+func PrivilegesRequired(v3Vector string) string {
+	return privilegesRequired[ParseCvssVector(v3Vector)["PR"]]
+}
@@ -0,0 +1,44 @@
+package scan
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVendorDataUnmarshal(t *testing.T) {
+	log.Debug().Msg("Inside TestVendorDataUnmarshal - Enter")
+	vendorJson := []byte(`[
+		{"id": "GHSA-mjmj-j48q-9wg2", "cvssV2": {"baseScore": -1}, "cvssV3": {"baseScore": 9.8, "exploitabilityScore": 3.9, "impactScore": 5.9, "vectorString": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}},
+		{"id": "CVE-2022-1471", "cvss_v3": {"base_metrics": {"base_score": 8.3, "exploitability_score": 2.8, "impact_score": 5.5}, "vector_string": "CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:H/A:H", "version": "3.1"}}
+	]`)
+	var vendorData []VendorData
+	err := json.Unmarshal(vendorJson, &vendorData)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(vendorData))
+	assert.Equal(t, 9.8, vendorData[0].CvssV3.BaseScore)
+	assert.Equal(t, -1.0, vendorData[0].CvssV2.BaseScore)
+	assert.Equal(t, 8.3, vendorData[1].CvssV3.BaseScore)
+	assert.Equal(t, 2.8, vendorData[1].CvssV3.ExploitabilityScore)
+	assert.Equal(t, "CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:H/A:H", vendorData[1].CvssV3.VectorString)
+	assert.Equal(t, "3.1", vendorData[1].CvssV3.Version)
+	log.Debug().Msg("Inside TestVendorDataUnmarshal - Exit")
+}
+
+func TestCvssVectors(t *testing.T) {
+	log.Debug().Msg("Inside TestCvssVectors - Enter")
+	v := VulnerabilityDetail{
+		VendorData: []VendorData{{Id: "GHSA-1", CvssV2: CvsScore{VectorString: "AV:N/AC:L/Au:N/C:P/I:P/A:P"}}},
+		NvdData:    []NvdData{{Id: "CVE-1", CvssV3: CvsScore{VectorString: "CVSS:3.1/AV:L/AC:L/PR:H/UI:N/S:U/C:H/I:H/A:H"}}},
+	}
+	v3Vector, v2Vector := v.CvssVectors()
+	assert.Equal(t, "CVSS:3.1/AV:L/AC:L/PR:H/UI:N/S:U/C:H/I:H/A:H", v3Vector)
+	assert.Equal(t, "AV:N/AC:L/Au:N/C:P/I:P/A:P", v2Vector)
+	assert.Equal(t, "LOCAL", AttackVector(v3Vector, v2Vector))
+	assert.Equal(t, "NETWORK", AttackVector("", v2Vector))
+	assert.Equal(t, "HIGH", PrivilegesRequired(v3Vector))
+	assert.Equal(t, "", PrivilegesRequired(""))
+	log.Debug().Msg("Inside TestCvssVectors - Exit")
+}
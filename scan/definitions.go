@@ -1,5 +1,7 @@
 package scan
 
+import "time"
+
 const EmptyString = ""
 const Slash = "/"
 const Url = "url"
@@ -18,29 +20,109 @@ const NexusRepo = "nexus_repo_binary"
 const AwsEcrRepo = "aws_ecr_repo"
 const RunningCommand = "Running command: %s"
 const StdErr = "stdout/err: "
+const BaseImageAuto = "auto"
+const ImageActive = "active"
+const Analyzed = "analyzed"
+const NotAnalyzed = "not_analyzed"
+const Analyzing = "analyzing"
+const AnalysisFailed = "analysis_failed"
+
+// analysis states of an image, as seen by the plugin
+const StateAnalyzed = "analyzed"
+const StatePending = "pending"
+const StateFailed = "failed"
+const StateInactive = "inactive"
+const StateUnknown = "unknown"
+
+// RetryPolicy is how long to wait for a pending analysis, Count checks are
+// made Sleep apart after the first one
+type RetryPolicy struct {
+	Count int
+	Sleep time.Duration
+}
+
+// DefaultRetryPolicy waits up to four minutes for an analysis
+var DefaultRetryPolicy = RetryPolicy{Count: RetryCount, Sleep: SleepDuration * time.Second}
 
 type GetAnalysisStatus struct {
 	AnalysisStatus string `json:"analysisStatus,omitempty"`
 	ImageStatus    string `json:"imageStatus,omitempty"`
+	ImageDigest    string `json:"imageDigest,omitempty"`
+	ParentDigest   string `json:"parentDigest,omitempty"`
+	CreatedAt      string `json:"createdAt,omitempty"`
+	LastUpdated    string `json:"lastUpdated,omitempty"`
+
+	AnalysisStatusDetail []AnalysisStatusDetail `json:"analysisStatusDetail,omitempty"`
+	ImageContent         ImageContent           `json:"imageContent,omitempty"`
+}
+
+type AnalysisStatusDetail struct {
+	FromStatus string         `json:"fromStatus,omitempty"`
+	ToStatus   string         `json:"toStatus,omitempty"`
+	Timestamp  string         `json:"timestamp,omitempty"`
+	Source     AnalysisSource `json:"source,omitempty"`
+}
+
+type AnalysisSource struct {
+	HostId      string `json:"hostid,omitempty"`
+	ServiceName string `json:"servicename,omitempty"`
+}
+
+type ImageContent struct {
+	Metadata ImageMetadata `json:"metadata,omitempty"`
+}
+
+type ImageMetadata struct {
+	Arch          string `json:"arch,omitempty"`
+	Distro        string `json:"distro,omitempty"`
+	DistroVersion string `json:"distroVersion,omitempty"`
 }
 
 type VulnerabilityDetail struct {
-	DetectedAt     string        `json:"detectedAt,omitempty"`
-	Feed           string        `json:"feed,omitempty"`
-	FeedGroup      string        `json:"feedGroup,omitempty"`
-	Fix            string        `json:"fix,omitempty"`
-	Package        string        `json:"package,omitempty"`
-	PackageCpe     string        `json:"packageCpe,omitempty"`
-	PackageName    string        `json:"packageName,omitempty"`
-	PackagePath    string        `json:"packagePath,omitempty"`
-	PackageType    string        `json:"packageType,omitempty"`
-	PackageVersion string        `json:"packageVersion,omitempty"`
-	Severity       string        `json:"severity,omitempty"`
-	Url            string        `json:"url,omitempty"`
-	CveId          string        `json:"vuln,omitempty"`
-	WillNotFix     bool          `json:"willNotFix,omitempty"`
-	VendorData     []interface{} `json:"vendorData"`
-	NvdData        []NvdData     `json:"nvdData,omitempty"`
+	DetectedAt     string       `json:"detectedAt,omitempty"`
+	PublishedDate  string       `json:"publishedDate,omitempty"`
+	Feed           string       `json:"feed,omitempty"`
+	FeedGroup      string       `json:"feedGroup,omitempty"`
+	Fix            string       `json:"fix,omitempty"`
+	Package        string       `json:"package,omitempty"`
+	PackageCpe     string       `json:"packageCpe,omitempty"`
+	PackageName    string       `json:"packageName,omitempty"`
+	PackagePath    string       `json:"packagePath,omitempty"`
+	PackageType    string       `json:"packageType,omitempty"`
+	PackageVersion string       `json:"packageVersion,omitempty"`
+	Severity       string       `json:"severity,omitempty"`
+	Url            string       `json:"url,omitempty"`
+	CveId          string       `json:"vuln,omitempty"`
+	WillNotFix     bool         `json:"willNotFix,omitempty"`
+	VendorData     []VendorData `json:"vendorData"`
+	NvdData        []NvdData    `json:"nvdData,omitempty"`
+
+	PackagePaths []string `json:"packagePaths,omitempty"`
+	Aliases      []string `json:"aliases,omitempty"`
+
+	SuppressedBy             string `json:"suppressedBy,omitempty"`
+	SuppressionJustification string `json:"suppressionJustification,omitempty"`
+	SuppressionExpires       string `json:"suppressionExpires,omitempty"`
+
+	EpssScore      float64 `json:"epssScore,omitempty"`
+	EpssPercentile float64 `json:"epssPercentile,omitempty"`
+	KnownExploited bool    `json:"knownExploited,omitempty"`
+	KevDateAdded   string  `json:"kevDateAdded,omitempty"`
+	KevDueDate     string  `json:"kevDueDate,omitempty"`
+
+	CvssV3Vector       string `json:"cvssV3Vector,omitempty"`
+	CvssV2Vector       string `json:"cvssV2Vector,omitempty"`
+	AttackVector       string `json:"attackVector,omitempty"`
+	PrivilegesRequired string `json:"privilegesRequired,omitempty"`
+
+	// InheritedFromBase is reported by the base image aware listing, nil when
+	// anchore could not compare the image with its base image
+	InheritedFromBase *bool  `json:"inheritedFromBase,omitempty"`
+	Origin            string `json:"origin,omitempty"`
+
+	DaysOpen    int    `json:"daysOpen,omitempty"`
+	SlaDueDate  string `json:"slaDueDate,omitempty"`
+	SlaBreached bool   `json:"slaBreached,omitempty"`
 }
 
 type NvdData struct {
@@ -49,10 +131,18 @@ type NvdData struct {
 	CvssV3 CvsScore `json:"cvssV3,omitempty"`
 }
 
+type VendorData struct {
+	Id     string   `json:"id,omitempty"`
+	CvssV2 CvsScore `json:"cvssV2,omitempty"`
+	CvssV3 CvsScore `json:"cvssV3,omitempty"`
+}
+
 type CvsScore struct {
 	BaseScore           float64 `json:"baseScore,omitempty"`
 	ExploitabilityScore float64 `json:"exploitabilityScore,omitempty"`
 	ImpactScore         float64 `json:"impactScore,omitempty"`
+	VectorString        string  `json:"vectorString,omitempty"`
+	Version             string  `json:"version,omitempty"`
 }
 
 type Registry struct {
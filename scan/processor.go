@@ -1,18 +1,58 @@
 package scan
 
 import (
+	"context"
+	"errors"
+	"os"
 	"os/exec"
 	"time"
 
 	"github.com/cloudbees-compliance/chlog-go/log"
 	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/config"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/metrics"
 )
 
-func (a AnchoreWrapper) GetImage(requestId string, imageName string) ([]byte, error) {
-	defer timeTrack(time.Now(), "Anchore get image", requestId)
+// processContext is cancelled when the plugin shuts down, which kills the
+// running anchorectl processes
+var processContext = context.Background()
+
+// SetProcessContext sets the context the anchorectl processes are bound to
+func SetProcessContext(ctx context.Context) {
+	processContext = ctx
+}
+
+type credentialsKey struct{}
+
+// WithCredentials returns a context carrying the anchore credentials of the
+// request, the anchorectl processes started with it get them in their own
+// environment so concurrent requests never see each other's credentials
+func WithCredentials(ctx context.Context, cred AccountCred) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, cred)
+}
+
+// CredentialsFrom returns the anchore credentials of the context
+func CredentialsFrom(ctx context.Context) AccountCred {
+	cred, _ := ctx.Value(credentialsKey{}).(AccountCred)
+	return cred
+}
+
+// anchorectlEnv is the environment of an anchorectl process using the credentials
+func anchorectlEnv(cred AccountCred) []string {
+	return append(os.Environ(),
+		"ANCHORECTL_URL="+cred.URL,
+		"ANCHORECTL_USERNAME="+cred.UserName,
+		"ANCHORECTL_PASSWORD="+cred.Password,
+		"ANCHORECTL_ACCOUNT="+cred.AccountName,
+		"ANCHORECTL_UPDATE_CHECK=false",
+	)
+}
+
+func (a AnchoreWrapper) GetImage(ctx context.Context, requestId string, imageName string) ([]byte, error) {
+	defer timeTrack(time.Now(), metrics.OpGetImage, "Anchore get image", requestId)
 	app := config.Config.GetString("anchorectl.exe")
 
-	cmd := exec.Command(app, "image", "get", imageName, "-o", "json")
+	cmd := exec.CommandContext(processContext, app, "image", "get", imageName, "-o", "json")
+	cmd.Env = anchorectlEnv(CredentialsFrom(ctx))
 	cmdString := cmd.String()
 
 	log.Debug(requestId).Msgf(RunningCommand, cmdString)
@@ -20,11 +60,18 @@ func (a AnchoreWrapper) GetImage(requestId string, imageName string) ([]byte, er
 	return cmd.CombinedOutput()
 }
 
-func (a AnchoreWrapper) GetVulnerabilities(requestId string, imageName string) ([]byte, error) {
-	defer timeTrack(time.Now(), "Anchore get vulnerabilities", requestId)
+func (a AnchoreWrapper) GetVulnerabilities(ctx context.Context, requestId string, imageName string, baseImage bool) ([]byte, error) {
+	defer timeTrack(time.Now(), metrics.OpGetVulnerabilities, "Anchore get vulnerabilities", requestId)
 	app := config.Config.GetString("anchorectl.exe")
 
-	cmd := exec.Command(app, "image", "vulnerabilities", imageName, "-t", "all", "-o", "json")
+	args := []string{"image", "vulnerabilities", imageName, "-t", "all", "-o", "json"}
+	if baseImage {
+		// anchore finds the base image from the image ancestry and tells for
+		// each vulnerability whether it is inherited from it
+		args = append(args, "--base-image", BaseImageAuto)
+	}
+	cmd := exec.CommandContext(processContext, app, args...)
+	cmd.Env = anchorectlEnv(CredentialsFrom(ctx))
 	cmdString := cmd.String()
 
 	log.Debug(requestId).Msgf(RunningCommand, cmdString)
@@ -33,11 +80,12 @@ func (a AnchoreWrapper) GetVulnerabilities(requestId string, imageName string) (
 
 }
 
-func (a AnchoreWrapper) GetRegistries(requestId string) ([]byte, error) {
-	defer timeTrack(time.Now(), "Anchore get registries", requestId)
+func (a AnchoreWrapper) GetRegistries(ctx context.Context, requestId string) ([]byte, error) {
+	defer timeTrack(time.Now(), metrics.OpGetRegistries, "Anchore get registries", requestId)
 	app := config.Config.GetString("anchorectl.exe")
 
-	cmd := exec.Command(app, "registry", "list", "-o", "json")
+	cmd := exec.CommandContext(processContext, app, "registry", "list", "-o", "json")
+	cmd.Env = anchorectlEnv(CredentialsFrom(ctx))
 	cmdString := cmd.String()
 
 	log.Debug(requestId).Msgf(RunningCommand, cmdString)
@@ -46,11 +94,12 @@ func (a AnchoreWrapper) GetRegistries(requestId string) ([]byte, error) {
 
 }
 
-func (a AnchoreWrapper) GetSystemStatus(requestId string) ([]byte, error) {
-	defer timeTrack(time.Now(), "Anchore status check", requestId)
+func (a AnchoreWrapper) GetSystemStatus(ctx context.Context, requestId string) ([]byte, error) {
+	defer timeTrack(time.Now(), metrics.OpSystemStatus, "Anchore status check", requestId)
 	app := config.Config.GetString("anchorectl.exe")
 
-	cmd := exec.Command(app, "system", "status")
+	cmd := exec.CommandContext(processContext, app, "system", "status")
+	cmd.Env = anchorectlEnv(CredentialsFrom(ctx))
 	cmdString := cmd.String()
 
 	log.Debug(requestId).Msgf(RunningCommand, cmdString)
@@ -58,7 +107,50 @@ func (a AnchoreWrapper) GetSystemStatus(requestId string) ([]byte, error) {
 	return cmd.CombinedOutput()
 }
 
-func timeTrack(start time.Time, name string, requestId string) {
+func timeTrack(start time.Time, operation string, name string, requestId string) {
+	metrics.ObserveCall(operation, start)
 	elapsed := time.Since(start)
 	log.Debug(requestId).Msgf("%s took %s", name, elapsed)
 }
+
+// CheckSystemStatus runs the system status check against the anchore of the
+// given credentials, without touching the environment of the running requests
+func CheckSystemStatus(requestId string, cred AccountCred) error {
+	defer timeTrack(time.Now(), metrics.OpStatusProbe, "Anchore status probe", requestId)
+	app := config.Config.GetString("anchorectl.exe")
+
+	cmd := exec.CommandContext(processContext, app, "system", "status")
+	cmd.Env = anchorectlEnv(cred)
+	log.Debug(requestId).Msgf(RunningCommand, cmd.String())
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		metrics.CountError(metrics.OpStatusProbe, metrics.ErrAnchorectl)
+	}
+	if err != nil && out != nil {
+		log.Error(requestId).Err(err).Msg(StdErr + string(out))
+	}
+	return err
+}
+
+// GetAnchorectlVersion runs anchorectl version and returns the version it reports
+func GetAnchorectlVersion(requestId string) (string, error) {
+	app := config.Config.GetString("anchorectl.exe")
+
+	cmd := exec.CommandContext(processContext, app, "version")
+	cmd.Env = append(os.Environ(), "ANCHORECTL_UPDATE_CHECK=false")
+	log.Debug(requestId).Msgf(RunningCommand, cmd.String())
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if out != nil {
+			log.Error(requestId).Err(err).Msg(StdErr + string(out))
+		}
+		return "", err
+	}
+	version := parseAnchorectlVersion(out)
+	if len(version) == 0 {
+		return "", errors.New("anchorectl did not report its version")
+	}
+	return version, nil
+}
@@ -4,16 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"os"
 	"strings"
+	"time"
 
 	log "github.com/cloudbees-compliance/chlog-go/log"
 	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
 	service "github.com/cloudbees-compliance/chplugin-go/v0.4.0/servicev0_4_0"
 	"github.com/cloudbees-compliance/chplugin-service-go/plugin"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/metrics"
 	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/tracing"
 	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/utilities"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type anchoreScanner struct {
@@ -32,7 +35,7 @@ func (as *anchoreScanner) GetManifest(context.Context, *service.GetManifestReque
 		Manifest: &domain.Manifest{
 			Uuid:    "8a300061-30f0-4cf6-ba79-33ee2f1c6151",
 			Name:    "AnchorePlugin",
-			Version: "0.0.1",
+			Version: manifestVersion(),
 			AssetRoles: []*domain.AssetRole{
 				{
 					AssetType:                AssetType,
@@ -51,12 +54,26 @@ func (as *anchoreScanner) GetAssetDescriptors(context.Context, *service.GetAsset
 	return &service.GetAssetDescriptorsResponse{}, nil
 }
 
-func (as *anchoreScanner) ExecuteAnalyser(ctx context.Context, req *service.ExecuteRequest, assetFetcher plugin.AssetFetcher, stream service.CHPluginService_AnalyserServer) (*service.ExecuteAnalyserResponse, error) {
+func (as *anchoreScanner) ExecuteAnalyser(ctx context.Context, req *service.ExecuteRequest, assetFetcher plugin.AssetFetcher, stream service.CHPluginService_AnalyserServer) (res *service.ExecuteAnalyserResponse, err error) {
 	log.Info().Msgf("Analyser Request received")
-	ctx = makeSubLogger(req, ctx)
+	defer func(start time.Time) {
+		outcome := "success"
+		if err != nil {
+			outcome = "error"
+		}
+		metrics.AnalyserDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+	}(time.Now())
+	if !analyses.begin() {
+		log.Warn().Msgf("Analyser Request rejected, plugin is shutting down")
+		return nil, ErrShuttingDown
+	}
+	defer analyses.end()
+	runtime := currentSettings()
+	ctx = makeSubLogger(req, ctx, runtime.LogLevel)
 	requestId := utilities.GetRequestId(ctx)
 	defer log.DestroySubLogger(requestId)
 
+	_, fetchSpan := tracing.Start(ctx, "FetchAssets")
 	receivedAssets, err := assetFetcher.FetchAssets(plugin.AssetFetchRequest{
 		AccountID:          req.Account.Uuid,
 		AssetType:          req.AssetType,
@@ -64,33 +81,17 @@ func (as *anchoreScanner) ExecuteAnalyser(ctx context.Context, req *service.Exec
 		Identifiers:        req.AssetIdentifiers,
 		ProfileIdentifiers: req.ProfileIdentifiers,
 	})
+	fetchSpan.SetAttributes(attribute.Int(tracing.AssetCount, len(receivedAssets)))
+	tracing.End(fetchSpan, err)
 	if err != nil {
 		log.Error(requestId).Err(err).Msgf("Asset Fetch Failed")
 		return nil, err
 	}
 
 	log.Debug(requestId).Msgf("Total Asset Fetched : %d", len(receivedAssets))
-	var checks []*domain.Evaluation
-	if len(receivedAssets) > 0 {
-		credMap, credError := makeCredentialMap(req, requestId)
-		if credError != nil {
-			return nil, credError
-		}
-		err := validateCredMap(credMap, requestId)
-		if err != nil {
-			return nil, err
-		}
-		log.Debug(requestId).Msgf("Anchore Auth Validate Success")
-		for _, asset := range receivedAssets {
-			for _, profile := range asset.Profiles {
-				log.Debug(requestId).Msgf("Binary Attributes Count : %v", len(profile.BinAttributes))
-				tagName := profile.Identifier
-				checks, err = processAssets(requestId, tagName, asset, profile)
-				if err != nil {
-					return nil, err
-				}
-			}
-		}
+	checks, err := analyseAssets(ctx, requestId, req, runtime, receivedAssets)
+	if err != nil {
+		return nil, err
 	}
 
 	return &service.ExecuteAnalyserResponse{
@@ -98,8 +99,47 @@ func (as *anchoreScanner) ExecuteAnalyser(ctx context.Context, req *service.Exec
 	}, nil
 }
 
-func processAssets(requestId, tagName string, asset *domain.Asset, profile *domain.AssetProfile) ([]*domain.Evaluation, error) {
+// analyseAssets analyses every asset profile with the credentials and the
+// analysis settings of the request
+func analyseAssets(ctx context.Context, requestId string, req *service.ExecuteRequest, runtime *RuntimeSettings, assets []*domain.Asset) ([]*domain.Evaluation, error) {
 	var checks []*domain.Evaluation
+	if len(assets) == 0 {
+		return checks, nil
+	}
+	credMap, credError := makeCredentialMap(req, requestId, runtime)
+	if credError != nil {
+		return nil, credError
+	}
+	ctx = scan.WithCredentials(ctx, credMap)
+	if err := scan.GetSystemStatus(ctx, requestId); err != nil {
+		return nil, err
+	}
+	log.Debug(requestId).Msgf("Anchore Auth Validate Success")
+	settings := makeAnalysisSettings(req, requestId, runtime)
+	for _, asset := range assets {
+		for _, profile := range asset.Profiles {
+			log.Debug(requestId).Msgf("Binary Attributes Count : %v", len(profile.BinAttributes))
+			tagName := profile.Identifier
+			profileChecks, err := processAssets(ctx, requestId, tagName, asset, profile, settings)
+			if err != nil {
+				metrics.AssetsProcessed.WithLabelValues(asset.MasterAsset.SubType, "error").Inc()
+				return nil, err
+			}
+			checks = append(checks, profileChecks...)
+		}
+	}
+	return checks, nil
+}
+
+func processAssets(ctx context.Context, requestId, tagName string, asset *domain.Asset, profile *domain.AssetProfile, settings AnalysisSettings) (checks []*domain.Evaluation, err error) {
+	ctx, span := tracing.Start(ctx, "ProcessAsset",
+		attribute.String(tracing.AssetSubType, asset.MasterAsset.SubType),
+		attribute.String(tracing.AssetIdentifier, asset.MasterAsset.Identifier),
+		attribute.String(tracing.ProfileIdentifier, profile.Identifier))
+	defer func() {
+		span.SetAttributes(attribute.Int(tracing.EvaluationCount, len(checks)))
+		tracing.End(span, err)
+	}()
 	assetIdentifier := asset.MasterAsset.Identifier
 	var imageName string
 	var imageDetails ImageDetails
@@ -116,71 +156,88 @@ func processAssets(requestId, tagName string, asset *domain.Asset, profile *doma
 		return nil, errors.New("invalid asset profile - Digest value or Tag Name not present ")
 	}
 
-	imageName, isAnalysed, err := getAnalysisStatus(asset, assetIdentifier, tagName, requestId, isImageDigest)
+	imageName, status, isAnalysed, err := getAnalysisStatus(ctx, asset, assetIdentifier, tagName, requestId, isImageDigest, settings.Retry)
 	if err != nil {
 		return nil, err
 	}
 	if isAnalysed {
-		vulnerabilityList, err := scan.GetVulnerabilities(requestId, imageName)
+		vulnerabilityList, err := scan.GetVulnerabilities(ctx, requestId, imageName, settings.BaseImageAttribution)
 		if err != nil {
 			return nil, err
 		}
-		if len(vulnerabilityList) > 0 {
-			log.Debug(requestId).Msgf("Vulnerabilities got %d", len(vulnerabilityList))
-			checks, err = buildEvaluations(requestId, &vulnerabilityList, asset, profile)
-			if err != nil {
-				log.Error(requestId).Err(err).Msgf("Error occurred while building evaluations %s", asset.MasterAsset.Identifier)
-				return nil, err
-			}
-			log.Info(requestId).Msgf("Total number of evaluations returned %d", len(checks))
-
-		} else {
+		span.SetAttributes(attribute.Int(tracing.VulnerabilityCount, len(vulnerabilityList)))
+		if settings.BaseImageAttribution {
+			attributeBaseImage(requestId, &vulnerabilityList)
+		}
+		if len(vulnerabilityList) == 0 {
 			log.Debug(requestId).Msgf("No Vulnerabilities")
 		}
+		log.Debug(requestId).Msgf("Vulnerabilities got %d", len(vulnerabilityList))
+		checks, err = buildEvaluations(requestId, &vulnerabilityList, asset, profile, imageName, status, settings)
+		if err != nil {
+			log.Error(requestId).Err(err).Msgf("Error occurred while building evaluations %s", asset.MasterAsset.Identifier)
+			return nil, err
+		}
+		log.Info(requestId).Msgf("Total number of evaluations returned %d", len(checks))
+		metrics.AssetsProcessed.WithLabelValues(asset.MasterAsset.SubType, scan.StateAnalyzed).Inc()
+	} else if status != nil && status.State() == scan.StateFailed {
+		log.Warn(requestId).Msgf("Anchore could not analyse %s, %s", imageName, status.FailureReason())
+		metrics.AssetsProcessed.WithLabelValues(asset.MasterAsset.SubType, scan.StateFailed).Inc()
+		return []*domain.Evaluation{mapToFailedEvaluation(requestId, asset, profile, imageName, status)}, nil
 	} else {
-		log.Error(requestId).Msgf("Could not get vulnerabilities %s", assetIdentifier)
+		state := scan.StateUnknown
+		if status != nil {
+			state = status.State()
+		}
+		log.Error(requestId).Msgf("Could not get vulnerabilities %s, image analysis is %s", assetIdentifier, state)
 		return nil, errors.New("could not get vulnerabilities")
 	}
 	return checks, nil
 }
 
-func getAnalysisStatus(asset *domain.Asset, assetIdentifier string, tagName string, requestId string, isImageDigest bool) (string, bool, error) {
-	var imageName string
-	var isAnalysed bool
-	var err error
+func getAnalysisStatus(ctx context.Context, asset *domain.Asset, assetIdentifier string, tagName string, requestId string, isImageDigest bool, retry scan.RetryPolicy) (imageName string, status *scan.GetAnalysisStatus, isAnalysed bool, err error) {
+	ctx, span := tracing.Start(ctx, "ResolveImage", attribute.String(tracing.AssetSubType, asset.MasterAsset.SubType))
+	defer func() {
+		span.SetAttributes(attribute.String(tracing.ImageReference, imageName))
+		if status != nil {
+			span.SetAttributes(attribute.String(tracing.ImageState, status.State()))
+		}
+		tracing.End(span, err)
+	}()
 	if isImageDigest {
-		_, isAnalysed, err = scan.GetScanStatus(requestId, tagName, scan.RetryCount)
-		return tagName, isAnalysed, err
+		status, isAnalysed, err = scan.GetScanStatus(ctx, requestId, tagName, retry)
+		return tagName, status, isAnalysed, err
 	} else {
 		if strings.Compare(scan.DockerRepo, asset.MasterAsset.SubType) == 0 {
 			assetIdentifier = strings.Replace(assetIdentifier, "library/", scan.EmptyString, -1)
 			imageName = assetIdentifier + ":" + tagName
-			_, isAnalysed, err = scan.GetScanStatus(requestId, imageName, scan.RetryCount)
+			status, isAnalysed, err = scan.GetScanStatus(ctx, requestId, imageName, retry)
 		} else if strings.Compare(scan.JfrogRepo, asset.MasterAsset.SubType) == 0 {
 			assetIdArr := strings.SplitAfter(assetIdentifier, "://")
 			imageNameStr := assetIdArr[1]
 			hostName := imageNameStr[0:strings.Index(imageNameStr, "/")]
 			assetName := imageNameStr[strings.Index(imageNameStr, "/artifactory")+len("/artifactory"):]
 			imageName = hostName + assetName + ":" + tagName
-			_, isAnalysed, err = scan.GetScanStatus(requestId, imageName, scan.RetryCount)
+			status, isAnalysed, err = scan.GetScanStatus(ctx, requestId, imageName, retry)
 		} else if strings.Compare(scan.NexusRepo, asset.MasterAsset.SubType) == 0 {
-			return getNexusAssetAnalysisStatus(assetIdentifier, requestId, tagName)
+			return getNexusAssetAnalysisStatus(ctx, assetIdentifier, requestId, tagName, retry)
 		} else if strings.Compare(scan.AwsEcrRepo, asset.MasterAsset.SubType) == 0 {
-			return getAwsEcrAssetAnalysisStatus(assetIdentifier, requestId, tagName)
+			return getAwsEcrAssetAnalysisStatus(ctx, assetIdentifier, requestId, tagName, retry)
 		}
-		return imageName, isAnalysed, err
+		return imageName, status, isAnalysed, err
 	}
 
 }
-func getNexusAssetAnalysisStatus(assetIdentifier, requestId, tagName string) (string, bool, error) {
+func getNexusAssetAnalysisStatus(ctx context.Context, assetIdentifier, requestId, tagName string, retry scan.RetryPolicy) (string, *scan.GetAnalysisStatus, bool, error) {
 	var imageName string
+	var status *scan.GetAnalysisStatus
 	var isAnalysed bool
 	var hostNameList []string
 	assetIdArr := strings.SplitAfter(assetIdentifier, "://")
 	imageNameStr := assetIdArr[1]
 	hostName := imageNameStr[0:strings.Index(imageNameStr, "/")]
 	assetName := imageNameStr[strings.Index(imageNameStr, ":v2")+len(":v2"):]
-	registryList, err := scan.GetRegistries(requestId)
+	registryList, err := scan.GetRegistries(ctx, requestId)
 	if err != nil {
 		log.Debug(requestId).Msgf("Could not get registry ... using default values")
 		for _, portNumber := range NexusPorts {
@@ -195,11 +252,11 @@ func getNexusAssetAnalysisStatus(assetIdentifier, requestId, tagName string) (st
 	}
 	if len(hostNameList) == 0 {
 		log.Error(requestId).Msgf("No Nexus registry found for asset %s", assetIdentifier)
-		return "", false, errors.New("no nexus registry found in anchore dashboard")
+		return "", nil, false, errors.New("no nexus registry found in anchore dashboard")
 	}
 	for _, hostNameValue := range hostNameList {
 		imageName = hostNameValue + assetName + ":" + tagName
-		_, isAnalysed, err = scan.GetScanStatus(requestId, imageName, scan.RetryCount)
+		status, isAnalysed, err = scan.GetScanStatus(ctx, requestId, imageName, retry)
 		if err == nil {
 			break
 		}
@@ -207,15 +264,15 @@ func getNexusAssetAnalysisStatus(assetIdentifier, requestId, tagName string) (st
 	}
 	if err != nil {
 		log.Error(requestId).Msgf("Could not get analysis status for Nexus asset %s", assetIdentifier)
-		return "", false, err
+		return "", nil, false, err
 	}
-	return imageName, isAnalysed, nil
+	return imageName, status, isAnalysed, nil
 }
 
-func getAwsEcrAssetAnalysisStatus(assetIdentifier, requestId, tagName string) (string, bool, error) {
+func getAwsEcrAssetAnalysisStatus(ctx context.Context, assetIdentifier, requestId, tagName string, retry scan.RetryPolicy) (string, *scan.GetAnalysisStatus, bool, error) {
 	splitedAssetIdentifer := strings.Split(assetIdentifier, ":")
 	assetName := strings.Replace(splitedAssetIdentifer[5], "repository", scan.EmptyString, -1)
-	registryList, err := scan.GetRegistries(requestId)
+	registryList, err := scan.GetRegistries(ctx, requestId)
 	registryName := scan.EmptyString
 	if err != nil {
 		log.Debug(requestId).Msgf("Could not get registry ... using default format")
@@ -229,51 +286,108 @@ func getAwsEcrAssetAnalysisStatus(assetIdentifier, requestId, tagName string) (s
 		}
 		if len(registryName) == 0 {
 			log.Error(requestId).Msgf("No Aws ECR registry found for asset %s", assetIdentifier)
-			return "", false, errors.New("no Aws Ecr registry found in anchore dashboard")
+			return "", nil, false, errors.New("no Aws Ecr registry found in anchore dashboard")
 		}
 	}
 	imageName := registryName + assetName + ":" + tagName
-	_, isAnalysed, err := scan.GetScanStatus(requestId, imageName, scan.RetryCount)
+	status, isAnalysed, err := scan.GetScanStatus(ctx, requestId, imageName, retry)
 	if err != nil {
 		log.Error(requestId).Msgf("Could not get analysis status for AWS ECR asset %s", assetIdentifier)
-		return "", false, err
+		return "", nil, false, err
 	}
-	return imageName, isAnalysed, nil
-}
-
-func makeCredentialMap(req *service.ExecuteRequest, requestId string) (scan.AccountCred, error) {
-	var credMap scan.AccountCred
-	if err := json.Unmarshal(req.Metadata, &credMap); err != nil {
-		log.Error(requestId).Err(err).Msgf("Error Parsing Credentials")
-		return scan.AccountCred{}, err
-	}
-	credMap.URL = utilities.GetValidURL(credMap.URL)
-	return credMap, nil
+	return imageName, status, isAnalysed, nil
 }
 
-func validateCredMap(credMap scan.AccountCred, requestId string) error {
-	os.Setenv("ANCHORECTL_ACCOUNT", credMap.AccountName)
-	os.Setenv("ANCHORECTL_PASSWORD", credMap.Password)
-	os.Setenv("ANCHORECTL_URL", credMap.URL)
-	os.Setenv("ANCHORECTL_USERNAME", credMap.UserName)
-	os.Setenv("ANCHORECTL_UPDATE_CHECK", "false")
-	return scan.GetSystemStatus(requestId)
+func makeAnalysisSettings(req *service.ExecuteRequest, requestId string, runtime *RuntimeSettings) AnalysisSettings {
+	settings := runtime.defaults()
+	if err := json.Unmarshal(req.Metadata, &settings); err != nil {
+		log.Warn(requestId).Err(err).Msgf("Error Parsing Analysis Settings, using defaults")
+	}
+	settings.Suppressions = append(settings.Suppressions, runtime.Suppressions...)
+	settings.EvaluationMode = strings.ToLower(settings.EvaluationMode)
+	if settings.EvaluationMode != VulnerabilityMode && settings.EvaluationMode != PackageMode {
+		log.Warn(requestId).Msgf("Evaluation mode : %s is defaulting to %s", settings.EvaluationMode, VulnerabilityMode)
+		settings.EvaluationMode = VulnerabilityMode
+	}
+	settings.SuppressionAction = strings.ToLower(settings.SuppressionAction)
+	if settings.SuppressionAction != SuppressionDrop && settings.SuppressionAction != SuppressionFlag {
+		log.Warn(requestId).Msgf("Suppression action : %s is defaulting to %s", settings.SuppressionAction, SuppressionFlag)
+		settings.SuppressionAction = SuppressionFlag
+	}
+	settings.KevImportance = strings.ToUpper(settings.KevImportance)
+	if _, ok := SeverityMap[settings.KevImportance]; !ok {
+		log.Warn(requestId).Msgf("KEV importance : %s is not valid, known exploited vulnerabilities are not escalated", settings.KevImportance)
+		settings.KevImportance = ""
+	}
+	settings.Filters = validateFilters(requestId, settings.Filters)
+	settings.Sla = validateSla(requestId, settings.Sla)
+	return settings
 }
 
-func buildEvaluations(requestId string, vulnList *[]scan.VulnerabilityDetail, asset *domain.Asset, ap *domain.AssetProfile) ([]*domain.Evaluation, error) {
+func buildEvaluations(requestId string, vulnList *[]scan.VulnerabilityDetail, asset *domain.Asset, ap *domain.AssetProfile, imageName string, status *scan.GetAnalysisStatus, settings AnalysisSettings) ([]*domain.Evaluation, error) {
 
 	evalList := []*domain.Evaluation{}
-	evaluationMap := mapToEvaluation(requestId, vulnList, asset, ap, map[string]*domain.Evaluation{})
-
-	if len(evaluationMap) > 0 {
+	filteredList := filterVulnerabilities(requestId, vulnList, settings.Filters)
+	normalizeAliases(requestId, filteredList)
+	sortVulnerabilities(filteredList)
+	filteredList = collapseDuplicatePackages(filteredList)
+	enrichVulnerabilities(&filteredList, settings.Feeds)
+	hasOrigin := isAttributed(filteredList)
+	hasAliases := isAliased(filteredList)
+	hasSla := applySla(requestId, filteredList, settings.Sla, time.Now())
+	activeList, suppressedList := applySuppressions(requestId, &filteredList, asset, imageName, settings)
+	categories, categoryMap := splitByCategory(activeList, settings)
+	for _, category := range categories {
+		categoryList := categoryMap[category]
+		evaluationMap := mapToModeEvaluation(requestId, &categoryList, asset, ap, settings)
+		setCategory(evaluationMap, category)
+		addEnrichment(requestId, evaluationMap, settings)
+		addOrigin(requestId, evaluationMap, hasOrigin)
+		addAliases(requestId, evaluationMap, hasAliases)
+		addSla(requestId, evaluationMap, settings.Sla, hasSla)
 		for _, evaluation := range evaluationMap {
 			evalList = append(evalList, evaluation)
 		}
 	}
+	if len(suppressedList) > 0 {
+		suppressedMap := mapToModeEvaluation(requestId, &suppressedList, asset, ap, settings)
+		addEnrichment(requestId, suppressedMap, settings)
+		addOrigin(requestId, suppressedMap, hasOrigin)
+		addAliases(requestId, suppressedMap, hasAliases)
+		// accepted risks keep their importance even when they are past the SLA
+		addSla(requestId, suppressedMap, SlaSettings{Days: settings.Sla.Days, Action: SlaFlag}, hasSla)
+		flagSuppressedEvaluations(requestId, suppressedMap)
+		for _, evaluation := range suppressedMap {
+			evalList = append(evalList, evaluation)
+		}
+	}
+	// package evaluations already carry the upgrade plan as their remediation
+	if settings.UpgradePlans && settings.EvaluationMode != PackageMode {
+		evalList = append(evalList, mapToUpgradePlanEvaluations(requestId, &activeList, asset, ap)...)
+	}
+	// the scan only passes when there is nothing else to report, flagged
+	// suppressions are still findings
+	if len(evalList) == 0 {
+		evalList = append(evalList, mapToPassedEvaluation(requestId, asset, ap, imageName, status))
+	}
+	if settings.ImageSummary {
+		evalList = append(evalList, mapToSummaryEvaluation(requestId, activeList, asset, ap, imageName, status))
+	}
+	for _, v := range activeList {
+		metrics.CountVulnerability(v.Severity)
+	}
+	sortEvaluations(requestId, evalList)
 	return evalList, nil
 }
 
-func makeSubLogger(req *service.ExecuteRequest, ctx context.Context) context.Context {
+func mapToModeEvaluation(requestId string, vulnList *[]scan.VulnerabilityDetail, asset *domain.Asset, ap *domain.AssetProfile, settings AnalysisSettings) map[string]*domain.Evaluation {
+	if settings.EvaluationMode == PackageMode {
+		return mapToPackageEvaluation(requestId, vulnList, asset, ap, map[string]*domain.Evaluation{})
+	}
+	return mapToEvaluation(requestId, vulnList, asset, ap, map[string]*domain.Evaluation{})
+}
+
+func makeSubLogger(req *service.ExecuteRequest, ctx context.Context, logLevel string) context.Context {
 	trackingInfo := make(map[string]string)
 	err := json.Unmarshal(req.TrackingInfo, &trackingInfo)
 	if err != nil {
@@ -285,7 +399,7 @@ func makeSubLogger(req *service.ExecuteRequest, ctx context.Context) context.Con
 		requestId = uuid.New().String()
 		trackingInfo[CHRequestId] = requestId
 	}
-	log.CreateSubLogger(requestId, "", trackingInfo)
+	log.CreateSubLogger(requestId, logLevel, trackingInfo)
 	ctx = context.WithValue(ctx, "requestId", requestId)
 	ctx = context.WithValue(ctx, "trackingInfo", trackingInfo)
 	return ctx
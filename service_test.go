@@ -4,14 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/cloudbees-compliance/chlog-go/log"
 	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
 	service "github.com/cloudbees-compliance/chplugin-go/v0.4.0/servicev0_4_0"
 	plugin "github.com/cloudbees-compliance/chplugin-service-go/plugin"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/metrics"
 	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
 	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/testdata"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/protobuf/proto"
 )
@@ -76,6 +80,7 @@ func TestGetManifest(t *testing.T) {
 	res, err := anchore.GetManifest(context.Background(), gmr)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(res.Manifest.AssetRoles))
+	assert.Equal(t, manifestVersion(), res.Manifest.Version)
 	log.Debug().Msg("Inside TestGetManifest - Exit")
 }
 
@@ -131,9 +136,15 @@ func TestBuildEvaluations(t *testing.T) {
 	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
 	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
 
-	evaluationList, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile)
+	evaluationList, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil, AnalysisSettings{EvaluationMode: VulnerabilityMode})
 	assert.Nil(t, err)
 	assert.NotNil(t, evaluationList)
+
+	evaluationList, err = buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil, AnalysisSettings{EvaluationMode: PackageMode})
+	assert.Nil(t, err)
+	for _, evaluation := range evaluationList {
+		assert.Contains(t, evaluation.Code, "@")
+	}
 	log.Debug().Msg("TestBuildEvaluations - Exit")
 }
 
@@ -149,6 +160,7 @@ func TestExecuteAnalyserSuccess(t *testing.T) {
 	log.Debug().Msg("TestExecuteAnalyserSuccess - Enter")
 	anchore := NewAnchoreScanner()
 	req := mockEcrExecuteRequest()
+	req.Metadata = []byte(`{"url":"testurl","userName":"test","password":"test","accountName":"test","imageSummary":true}`)
 	fetcher := &PluginFetcher{}
 
 	testdata.MockGetSystemStatus("testdata/getsystemstatus.json")
@@ -161,6 +173,15 @@ func TestExecuteAnalyserSuccess(t *testing.T) {
 	res, err := anchore.ExecuteAnalyser(context.Background(), req, fetcher, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, res)
+	summaries := 0
+	for _, check := range res.Checks {
+		assert.NotEqual(t, ScanPassedCode, check.Code)
+		if check.Code == SummaryCode {
+			summaries++
+		}
+	}
+	// the evaluations of every asset profile are returned
+	assert.Equal(t, 4, summaries)
 	log.Debug().Msg("TestExecuteAnalyserSuccess - Exit")
 }
 
@@ -180,6 +201,62 @@ func TestExecuteAnalyserCredErr(t *testing.T) {
 	log.Debug().Msg("TestExecuteAnalyserCredErr - Exit")
 }
 
+func TestExecuteAnalyserConcurrentCredentials(t *testing.T) {
+	log.Debug().Msg("TestExecuteAnalyserConcurrentCredentials - Enter")
+	InitConfig()
+	t.Setenv("ANCHORECTL_PASSWORD", "process")
+	testdata.MockGetSystemStatus("testdata/getsystemstatus.json")
+	testdata.MockGetRegistries("testdata/getregistries.json")
+	testdata.MockGetImage("testdata/getimage.json")
+	testdata.MockGetVulnerabilities("testdata/getVulnerabilities.json")
+
+	// every anchorectl call of a request gets the credentials of the request
+	var passwords sync.Map
+	var mixed atomic.Bool
+	record := func(ctx context.Context, requestId string) {
+		password := scan.CredentialsFrom(ctx).Password
+		if previous, loaded := passwords.LoadOrStore(requestId, password); loaded && previous != password {
+			mixed.Store(true)
+		}
+	}
+	getSystemStatus, getImage, getVulnerabilities := testdata.GetSystemStatusMock, testdata.GetImageMock, testdata.GetVulnerabilitiesMock
+	testdata.GetSystemStatusMock = func(ctx context.Context, requestId string) ([]byte, error) {
+		record(ctx, requestId)
+		return getSystemStatus(ctx, requestId)
+	}
+	testdata.GetImageMock = func(ctx context.Context, requestId string, imageName string) ([]byte, error) {
+		record(ctx, requestId)
+		return getImage(ctx, requestId, imageName)
+	}
+	testdata.GetVulnerabilitiesMock = func(ctx context.Context, requestId string, imageName string, baseImage bool) ([]byte, error) {
+		record(ctx, requestId)
+		return getVulnerabilities(ctx, requestId, imageName, baseImage)
+	}
+	scan.IAnchore = testdata.HttpMock1{}
+
+	var wg sync.WaitGroup
+	for _, password := range []string{"secret-a", "secret-b", "secret-c"} {
+		wg.Add(1)
+		go func(password string) {
+			defer wg.Done()
+			req := mockEcrExecuteRequest()
+			req.Metadata = []byte(`{"url":"testurl","userName":"test","password":"` + password + `","accountName":"test"}`)
+			_, err := NewAnchoreScanner().ExecuteAnalyser(context.Background(), req, &PluginFetcher{}, nil)
+			assert.Nil(t, err)
+		}(password)
+	}
+	wg.Wait()
+	assert.False(t, mixed.Load())
+	seen := map[interface{}]bool{}
+	passwords.Range(func(_, password interface{}) bool {
+		seen[password] = true
+		return true
+	})
+	assert.Equal(t, map[interface{}]bool{"secret-a": true, "secret-b": true, "secret-c": true}, seen)
+	assert.Equal(t, "process", os.Getenv("ANCHORECTL_PASSWORD"))
+	log.Debug().Msg("TestExecuteAnalyserConcurrentCredentials - Exit")
+}
+
 func TestExecuteAnalyserGetStatusErr(t *testing.T) {
 	log.Debug().Msg("TestExecuteAnalyserGetStatusErr - Enter")
 	anchore := NewAnchoreScanner()
@@ -233,7 +310,27 @@ func TestExecuteAnalyserEmptyVuln(t *testing.T) {
 	scan.IAnchore = mockVar
 	res, err := anchore.ExecuteAnalyser(context.Background(), req, fetcher, nil)
 	assert.Nil(t, err)
-	assert.Equal(t, 0, len(res.Checks))
+	passed := 0
+	for _, check := range res.Checks {
+		assert.Contains(t, []string{ScanCategory, SummaryCategory}, *check.Category)
+		if check.Code == ScanPassedCode {
+			passed++
+			// the passed scan is tied to the asset profile it is for
+			assert.Equal(t, 1, len(check.Failures))
+			assert.Equal(t, "testProfileuuid", check.Failures[0].ProfileUuid)
+			assert.NotEmpty(t, check.Failures[0].AssetUuid)
+			assert.Equal(t, ScanPassed, check.Failures[0].Details[0].Data[3])
+			assert.Equal(t, len(check.DetailHeaders), len(check.Failures[0].Details[0].Data))
+			var result ScanResult
+			assert.Nil(t, json.Unmarshal(check.BaseData, &result))
+			assert.Equal(t, ScanPassed, result.Result)
+			assert.Equal(t, "2023-08-25T07:23:08Z", result.AnalysedAt)
+			assert.NotEmpty(t, result.ProfileUuid)
+		}
+	}
+	// one passed evaluation per analysed asset profile
+	assert.Equal(t, 4, passed)
+	assert.GreaterOrEqual(t, testutil.ToFloat64(metrics.AssetsProcessed.WithLabelValues("aws_ecr_repo", scan.StateAnalyzed)), 1.0)
 	log.Debug().Msg("TestExecuteAnalyserEmptyVuln - Exit")
 }
 
@@ -256,6 +353,31 @@ func TestExecuteAnalyserInactiveImage(t *testing.T) {
 	log.Debug().Msg("TestExecuteAnalyserInactiveImage - Exit")
 }
 
+func TestExecuteAnalyserFailedImage(t *testing.T) {
+	log.Debug().Msg("TestExecuteAnalyserFailedImage - Enter")
+	anchore := NewAnchoreScanner()
+	req := mockEcrExecuteRequest()
+	fetcher := &PluginFetcher{}
+
+	testdata.MockGetSystemStatus("testdata/getsystemstatus.json")
+	testdata.MockGetRegistries("testdata/getregistries.json")
+	testdata.MockGetImage("testdata/getfailedimage.json")
+	testdata.MockGetVulnerabilities("testdata/getVulnerabilities.json")
+	mockVar := testdata.HttpMock1{}
+
+	scan.IAnchore = mockVar
+	res, err := anchore.ExecuteAnalyser(context.Background(), req, fetcher, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(res.Checks))
+	for _, check := range res.Checks {
+		assert.Equal(t, ScanFailedCode, check.Code)
+		assert.Equal(t, ScanCategory, *check.Category)
+		assert.Equal(t, "failed", check.Failures[0].Details[0].Data[3])
+		assert.Contains(t, check.Failures[0].Details[0].Data[4], "analysis failed at 2023-08-25T07:23:08Z")
+	}
+	log.Debug().Msg("TestExecuteAnalyserFailedImage - Exit")
+}
+
 func TestExecuteAnalyserGetRegistryErr(t *testing.T) {
 	log.Debug().Msg("TestExecuteAnalyserGetRegistryErr - Enter")
 	anchore := NewAnchoreScanner()
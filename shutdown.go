@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	"google.golang.org/grpc"
+)
+
+// ShutdownKillWait is how long the cancelled analyses get to return after
+// their anchorectl processes were killed
+const ShutdownKillWait = 5 * time.Second
+
+var ErrShuttingDown = errors.New("plugin is shutting down, analysis not started")
+
+// drainer tracks the in-flight analyses so a shutdown can wait for them
+type drainer struct {
+	mu       sync.Mutex
+	draining bool
+	wg       sync.WaitGroup
+}
+
+// analyses are the ExecuteAnalyser calls in flight
+var analyses = &drainer{}
+
+// begin registers a new analysis, it returns false once the shutdown started
+func (d *drainer) begin() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.wg.Add(1)
+	return true
+}
+
+func (d *drainer) end() {
+	d.wg.Done()
+}
+
+// drain stops new analyses and waits up to the timeout for the running ones,
+// it returns false when they did not finish in time
+func (d *drainer) drain(timeout time.Duration) bool {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// shutdown stops accepting RPCs, waits for the in-flight analyses for the grace
+// period, then cancels them, killing their anchorectl processes, and stops the server
+func shutdown(gRPCServer *grpc.Server, checker *healthChecker, cancelProcesses context.CancelFunc, grace time.Duration) {
+	log.Info().Msgf("Shutting down, waiting up to %s for in-flight analyses", grace)
+	checker.server.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		gRPCServer.GracefulStop()
+		close(stopped)
+	}()
+	if analyses.drain(grace) {
+		log.Info().Msg("In-flight analyses finished")
+	} else {
+		log.Warn().Msg("Grace period expired, cancelling in-flight analyses")
+		cancelProcesses()
+		if !analyses.drain(ShutdownKillWait) {
+			log.Warn().Msg("Analyses still running after cancellation")
+		}
+	}
+	select {
+	case <-stopped:
+	case <-time.After(ShutdownKillWait):
+		log.Warn().Msg("Closing the remaining streams")
+		gRPCServer.Stop()
+	}
+	cancelProcesses()
+	log.Info().Msg("Shutdown complete")
+}
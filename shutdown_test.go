@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+)
+
+func TestDrainer(t *testing.T) {
+	log.Debug().Msg("Inside TestDrainer - Enter")
+	d := &drainer{}
+	assert.True(t, d.begin())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		d.end()
+	}()
+	assert.True(t, d.drain(time.Second))
+	assert.False(t, d.begin())
+
+	d = &drainer{}
+	assert.True(t, d.begin())
+	assert.False(t, d.drain(10*time.Millisecond))
+	d.end()
+	log.Debug().Msg("Inside TestDrainer - Exit")
+}
+
+func TestShutdownCancelsAnalyses(t *testing.T) {
+	log.Debug().Msg("Inside TestShutdownCancelsAnalyses - Enter")
+	saved := analyses
+	defer func() { analyses = saved }()
+	analyses = &drainer{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.True(t, analyses.begin())
+	// an analysis which only returns once its processes are cancelled
+	go func() {
+		<-ctx.Done()
+		analyses.end()
+	}()
+	checker := &healthChecker{server: health.NewServer()}
+	start := time.Now()
+	shutdown(grpc.NewServer(), checker, cancel, 50*time.Millisecond)
+	assert.NotNil(t, ctx.Err())
+	assert.Less(t, time.Since(start), ShutdownKillWait)
+	assert.False(t, analyses.begin())
+	log.Debug().Msg("Inside TestShutdownCancelsAnalyses - Exit")
+}
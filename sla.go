@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+)
+
+// SlaSeverities are the anchore severities an SLA can be configured for
+var SlaSeverities = []string{"critical", "high", "medium", "low", "negligible", "unknown"}
+
+func validateSla(requestId string, sla SlaSettings) SlaSettings {
+	days := map[string]int{}
+	for severity, d := range sla.Days {
+		severity = strings.ToLower(severity)
+		if _, ok := AnchoreSeverityRank[severity]; !ok || d < 0 {
+			log.Warn(requestId).Msgf("SLA of %d days for severity : %s is not valid, ignoring it", d, severity)
+			continue
+		}
+		days[severity] = d
+	}
+	sla.Days = days
+	sla.Action = strings.ToLower(sla.Action)
+	if sla.Action != SlaFlag && sla.Action != SlaEscalate {
+		log.Warn(requestId).Msgf("SLA action : %s is defaulting to %s", sla.Action, SlaFlag)
+		sla.Action = SlaFlag
+	}
+	return sla
+}
+
+// applySla sets the days open and the SLA due date of the findings. The SLA
+// clock starts when anchore detected the finding, but not before the CVE was
+// published. It returns false when no severity has an SLA.
+func applySla(requestId string, vulnList []scan.VulnerabilityDetail, sla SlaSettings, now time.Time) bool {
+	enabled := false
+	for _, d := range sla.Days {
+		enabled = enabled || d > 0
+	}
+	if !enabled {
+		return false
+	}
+	for i := range vulnList {
+		v := &vulnList[i]
+		start, ok := slaStart(*v)
+		if !ok {
+			log.Debug(requestId).Msgf("No detection date for %s in %s, SLA not tracked", v.CveId, v.Package)
+			continue
+		}
+		v.DaysOpen = int(now.Sub(start).Hours() / 24)
+		days := sla.Days[strings.ToLower(v.Severity)]
+		if days == 0 {
+			continue
+		}
+		due := start.AddDate(0, 0, days)
+		v.SlaDueDate = due.Format("2006-01-02")
+		v.SlaBreached = now.After(due)
+	}
+	return true
+}
+
+func slaStart(v scan.VulnerabilityDetail) (time.Time, bool) {
+	detected, ok := parseSlaDate(v.DetectedAt)
+	if !ok {
+		return detected, false
+	}
+	if published, ok := parseSlaDate(v.PublishedDate); ok && published.After(detected) {
+		return published, true
+	}
+	return detected, true
+}
+
+func parseSlaDate(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// addSla adds the SLA columns and, when the action is escalate, raises the
+// importance of the evaluations with a finding past its SLA by one level
+func addSla(reqId string, evalMap map[string]*domain.Evaluation, sla SlaSettings, hasSla bool) {
+	if !hasSla {
+		return
+	}
+	for _, eval := range evalMap {
+		breached := false
+		appendDetailColumns(reqId, eval, []string{"Days Open", "SLA Due Date", "SLA Breached"}, []string{String, String, String},
+			[]string{Summary, Summary, Summary},
+			func(v scan.VulnerabilityDetail) []string {
+				breached = breached || v.SlaBreached
+				return []string{strconv.Itoa(v.DaysOpen), v.SlaDueDate, strconv.FormatBool(v.SlaBreached)}
+			})
+		if breached && sla.Action == SlaEscalate {
+			eval.Importance = escalateImportance(eval.Importance)
+		}
+	}
+}
+
+func escalateImportance(importance string) string {
+	for i, name := range ImportanceOrder[:len(ImportanceOrder)-1] {
+		if name == importance {
+			return ImportanceOrder[i+1]
+		}
+	}
+	return importance
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSla(t *testing.T) {
+	log.Debug().Msg("Inside TestValidateSla - Enter")
+	sla := validateSla("123", SlaSettings{Days: map[string]int{"Critical": 15, "high": -1, "urgent": 5}, Action: "Escalate"})
+	assert.Equal(t, map[string]int{"critical": 15}, sla.Days)
+	assert.Equal(t, SlaEscalate, sla.Action)
+	assert.Equal(t, SlaFlag, validateSla("123", SlaSettings{Action: "page"}).Action)
+	log.Debug().Msg("Inside TestValidateSla - Exit")
+}
+
+func TestApplySla(t *testing.T) {
+	log.Debug().Msg("Inside TestApplySla - Enter")
+	now := time.Date(2023, 8, 28, 12, 0, 0, 0, time.UTC)
+	sla := SlaSettings{Days: map[string]int{"critical": 15, "low": 180}, Action: SlaFlag}
+	vulnList := []scan.VulnerabilityDetail{
+		{CveId: "CVE-1", Severity: "Critical", DetectedAt: "2023-07-28T12:25:51Z"},
+		{CveId: "CVE-2", Severity: "Low", DetectedAt: "2023-07-28T12:25:51Z"},
+		{CveId: "CVE-3", Severity: "Critical", DetectedAt: "2023-07-28T12:25:51Z", PublishedDate: "2023-08-20"},
+		{CveId: "CVE-4", Severity: "Medium", DetectedAt: "2023-07-28T12:25:51Z"},
+		{CveId: "CVE-5", Severity: "Critical"},
+	}
+	assert.True(t, applySla("123", vulnList, sla, now))
+
+	assert.Equal(t, 30, vulnList[0].DaysOpen)
+	assert.Equal(t, "2023-08-12", vulnList[0].SlaDueDate)
+	assert.True(t, vulnList[0].SlaBreached)
+
+	assert.Equal(t, "2024-01-24", vulnList[1].SlaDueDate)
+	assert.False(t, vulnList[1].SlaBreached)
+
+	assert.Equal(t, 8, vulnList[2].DaysOpen)
+	assert.Equal(t, "2023-09-04", vulnList[2].SlaDueDate)
+	assert.False(t, vulnList[2].SlaBreached)
+
+	assert.Equal(t, 30, vulnList[3].DaysOpen)
+	assert.Empty(t, vulnList[3].SlaDueDate)
+	assert.False(t, vulnList[3].SlaBreached)
+
+	assert.Zero(t, vulnList[4].DaysOpen)
+	assert.Empty(t, vulnList[4].SlaDueDate)
+
+	assert.False(t, applySla("123", vulnList, SlaSettings{Days: map[string]int{"critical": 0}}, now))
+	log.Debug().Msg("Inside TestApplySla - Exit")
+}
+
+func TestAddSla(t *testing.T) {
+	log.Debug().Msg("Inside TestAddSla - Enter")
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+	vulnList := []scan.VulnerabilityDetail{
+		{CveId: "CVE-1", Severity: "Medium", PackageName: "openssl", PackageVersion: "1.1", DetectedAt: "2020-01-01T00:00:00Z"},
+		{CveId: "CVE-2", Severity: "Medium", PackageName: "openssl", PackageVersion: "1.1", DetectedAt: time.Now().UTC().Format(time.RFC3339)},
+	}
+	for _, action := range []string{SlaFlag, SlaEscalate} {
+		list := append([]scan.VulnerabilityDetail{}, vulnList...)
+		evalList, err := buildEvaluations("123", &list, asset, assetProfile, "localhost:v1.0.1", nil,
+			AnalysisSettings{EvaluationMode: VulnerabilityMode, Sla: SlaSettings{Days: map[string]int{"medium": 90}, Action: action}})
+		assert.Nil(t, err)
+		for _, eval := range evalList {
+			if eval.Code != "CVE-1" && eval.Code != "CVE-2" {
+				continue
+			}
+			breachedColumn := indexOf(eval.DetailHeaders, "SLA Breached")
+			assert.GreaterOrEqual(t, breachedColumn, 0)
+			breached := eval.Failures[0].Details[0].Data[breachedColumn] == "true"
+			assert.Equal(t, eval.Code == "CVE-1", breached)
+			if breached && action == SlaEscalate {
+				assert.Equal(t, "HIGH", eval.Importance)
+			} else {
+				assert.Equal(t, "MEDIUM", eval.Importance)
+			}
+		}
+	}
+	assert.Equal(t, "VERY_HIGH", escalateImportance("VERY_HIGH"))
+	assert.Equal(t, "MEDIUM", escalateImportance("LOW"))
+	log.Debug().Msg("Inside TestAddSla - Exit")
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+)
+
+// SummarySeverities are the anchore severities counted by the image summary
+var SummarySeverities = []string{"Critical", "High", "Medium", "Low", "Negligible", "Unknown"}
+
+// summarizeImage counts the reported findings of an image so dashboards do not
+// have to aggregate the vulnerability evaluations themselves
+func summarizeImage(vulnList []scan.VulnerabilityDetail, imageName string, status *scan.GetAnalysisStatus) *ImageSummary {
+	summary := &ImageSummary{Image: imageName, Severities: map[string]int{}}
+	for _, severity := range SummarySeverities {
+		summary.Severities[severity] = 0
+	}
+	if status != nil {
+		summary.ImageDigest = status.ImageDigest
+		summary.Distro = status.ImageContent.Metadata.Distro
+		summary.DistroVersion = status.ImageContent.Metadata.DistroVersion
+		summary.AnalysedAt = status.AnalysedAt()
+	}
+	for _, v := range vulnList {
+		summary.Total++
+		summary.Severities[summarySeverity(v.Severity)]++
+		if hasFix(v) {
+			summary.Fixable++
+		} else {
+			summary.Unfixable++
+		}
+		if score := maxCvss(v); score > summary.HighestCvss {
+			summary.HighestCvss = score
+		}
+	}
+	return summary
+}
+
+func summarySeverity(severity string) string {
+	for _, s := range SummarySeverities {
+		if strings.EqualFold(s, severity) {
+			return s
+		}
+	}
+	return "Unknown"
+}
+
+// importance is the importance of the most severe finding of the image
+func (s *ImageSummary) importance(reqId string) string {
+	for _, severity := range SummarySeverities {
+		if s.Severities[severity] > 0 && severity != "Unknown" {
+			return mapSeverity(reqId, severity)
+		}
+	}
+	return "LOW"
+}
+
+func mapToSummaryEvaluation(reqId string, vulnList []scan.VulnerabilityDetail, asset *domain.Asset, ap *domain.AssetProfile, imageName string, status *scan.GetAnalysisStatus) *domain.Evaluation {
+	summaryCategory := SummaryCategory
+	summary := summarizeImage(vulnList, imageName, status)
+	headers := []string{"Image", "Image Digest", "Distro", "Distro Version", "Analysed At", "Total"}
+	types := []string{String, String, String, String, String, String}
+	contexts := []string{Summary, Summary, Summary, Summary, Summary, Summary}
+	row := []string{summary.Image, summary.ImageDigest, summary.Distro, summary.DistroVersion, summary.AnalysedAt, strconv.Itoa(summary.Total)}
+	for _, severity := range SummarySeverities {
+		headers = append(headers, severity)
+		types = append(types, String)
+		contexts = append(contexts, Summary)
+		row = append(row, strconv.Itoa(summary.Severities[severity]))
+	}
+	headers = append(headers, "Fixable", "Unfixable", "Highest CVSS")
+	types = append(types, String, String, String)
+	contexts = append(contexts, Detail, Detail, Detail)
+	row = append(row, strconv.Itoa(summary.Fixable), strconv.Itoa(summary.Unfixable), strconv.FormatFloat(summary.HighestCvss, 'f', 1, 64))
+	remediation := fmt.Sprintf("%d of %d findings have a fix available", summary.Fixable, summary.Total)
+	return &domain.Evaluation{
+		Standard:       "STANDARD",
+		Code:           SummaryCode,
+		Name:           "Vulnerability summary of " + imageName,
+		Importance:     summary.importance(reqId),
+		DetailHeaders:  headers,
+		DetailTypes:    types,
+		DetailContexts: contexts,
+		Category:       &summaryCategory,
+		Failures: []*domain.AssetResult{{
+			Asset:          asset.MasterAsset,
+			AssetUuid:      asset.Uuid,
+			AttributesUuid: ap.AttributesUuid,
+			ProfileUuid:    ap.Uuid,
+			Details:        []*domain.DetailRow{{Data: row}},
+		}},
+		BaseData:    makeJsonBytes(summary, reqId, "ImageSummary"),
+		Remediation: &remediation,
+	}
+}
+
+// mapToPassedEvaluation reports an analysed image without any finding, so the
+// hub can tell a clean image apart from one that was never scanned. The asset
+// result ties the passed scan to the asset profile, like a failed analysis.
+func mapToPassedEvaluation(reqId string, asset *domain.Asset, ap *domain.AssetProfile, imageName string, status *scan.GetAnalysisStatus) *domain.Evaluation {
+	scanCategory := ScanCategory
+	remediation := NoFix
+	result := ScanResult{
+		AssetUuid:      asset.Uuid,
+		ProfileUuid:    ap.Uuid,
+		AttributesUuid: ap.AttributesUuid,
+		Image:          imageName,
+		Result:         ScanPassed,
+	}
+	if status != nil {
+		result.ImageDigest = status.ImageDigest
+		result.AnalysedAt = status.AnalysedAt()
+	}
+	log.Info(reqId).Msgf("No vulnerabilities reported for %s, vulnerability scan passed", imageName)
+	return &domain.Evaluation{
+		Standard:       "STANDARD",
+		Code:           ScanPassedCode,
+		Name:           "Vulnerability scan passed for " + imageName,
+		Importance:     "LOW",
+		DetailHeaders:  []string{"Image", "Image Digest", "Analysed At", "Result"},
+		DetailTypes:    []string{String, String, String, String},
+		DetailContexts: []string{Summary, Summary, Summary, Summary},
+		Category:       &scanCategory,
+		Failures: []*domain.AssetResult{{
+			Asset:          asset.MasterAsset,
+			AssetUuid:      asset.Uuid,
+			AttributesUuid: ap.AttributesUuid,
+			ProfileUuid:    ap.Uuid,
+			Details:        []*domain.DetailRow{{Data: []string{imageName, result.ImageDigest, result.AnalysedAt, result.Result}}},
+		}},
+		BaseData:    makeJsonBytes(result, reqId, "ScanResult"),
+		Remediation: &remediation,
+	}
+}
+
+// mapToFailedEvaluation reports an image anchore could not analyse, with the
+// failure from the analysis status history
+func mapToFailedEvaluation(reqId string, asset *domain.Asset, ap *domain.AssetProfile, imageName string, status *scan.GetAnalysisStatus) *domain.Evaluation {
+	scanCategory := ScanCategory
+	remediation := "Check the anchore analyzer logs and submit the image for analysis again"
+	return &domain.Evaluation{
+		Standard:       "STANDARD",
+		Code:           ScanFailedCode,
+		Name:           "Vulnerability scan failed for " + imageName,
+		Importance:     "HIGH",
+		DetailHeaders:  []string{"Image", "Image Digest", "Failed At", "Result", "Reason"},
+		DetailTypes:    []string{String, String, String, String, String},
+		DetailContexts: []string{Summary, Summary, Summary, Summary, Summary},
+		Category:       &scanCategory,
+		Failures: []*domain.AssetResult{{
+			Asset:          asset.MasterAsset,
+			AssetUuid:      asset.Uuid,
+			AttributesUuid: ap.AttributesUuid,
+			ProfileUuid:    ap.Uuid,
+			Details:        []*domain.DetailRow{{Data: []string{imageName, status.ImageDigest, status.FailedAt(), "failed", status.FailureReason()}}},
+		}},
+		BaseData:    makeJsonBytes(status, reqId, "GetAnalysisStatus"),
+		Remediation: &remediation,
+	}
+}
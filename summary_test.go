@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeImage(t *testing.T) {
+	log.Debug().Msg("Inside TestSummarizeImage - Enter")
+	var vulnerabilityList []scan.VulnerabilityDetail
+	vulnerabilitiesByte, _ := os.ReadFile("testdata/getVulnerabilities.json")
+	json.Unmarshal(vulnerabilitiesByte, &vulnerabilityList)
+	var status scan.GetAnalysisStatus
+	statusByte, _ := os.ReadFile("testdata/getimage.json")
+	json.Unmarshal(statusByte, &status)
+
+	summary := summarizeImage(vulnerabilityList, "localhost:v1.0.1", &status)
+	assert.Equal(t, "sha256:e2e16842c9b54d985bf1ef9242a313f36b856181f188de21313820e177002501", summary.ImageDigest)
+	assert.Equal(t, "alpine", summary.Distro)
+	assert.Equal(t, "3.17.2", summary.DistroVersion)
+	assert.Equal(t, "2023-08-25T07:23:08Z", summary.AnalysedAt)
+	assert.Equal(t, len(vulnerabilityList), summary.Total)
+	assert.Equal(t, summary.Total, summary.Fixable+summary.Unfixable)
+	total := 0
+	for _, count := range summary.Severities {
+		total += count
+	}
+	assert.Equal(t, summary.Total, total)
+	log.Debug().Msg(" TestSummarizeImage - Exit")
+}
+
+func TestMapToSummaryEvaluation(t *testing.T) {
+	log.Debug().Msg("Inside TestMapToSummaryEvaluation - Enter")
+	var vulnerabilityList []scan.VulnerabilityDetail
+	vulnerabilitiesByte, _ := os.ReadFile("testdata/getVulnerabilities.json")
+	json.Unmarshal(vulnerabilitiesByte, &vulnerabilityList)
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+
+	evalList, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil,
+		AnalysisSettings{EvaluationMode: VulnerabilityMode, ImageSummary: true})
+	assert.Nil(t, err)
+	var summaryEval *domain.Evaluation
+	for _, eval := range evalList {
+		if getCategory(eval) == SummaryCategory {
+			assert.Nil(t, summaryEval)
+			summaryEval = eval
+		}
+	}
+	assert.NotNil(t, summaryEval)
+	assert.Equal(t, SummaryCode, summaryEval.Code)
+	assert.Equal(t, len(summaryEval.DetailHeaders), len(summaryEval.Failures[0].Details[0].Data))
+	assert.Equal(t, []string{"localhost:v1.0.1", "", "", "", "", "149", "2", "41", "37", "9", "58", "2", "48", "101", "10.0"},
+		summaryEval.Failures[0].Details[0].Data)
+	assert.Equal(t, "VERY_HIGH", summaryEval.Importance)
+	assert.Equal(t, "48 of 149 findings have a fix available", *summaryEval.Remediation)
+	log.Debug().Msg(" TestMapToSummaryEvaluation - Exit")
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+)
+
+var expiryLayouts = []string{time.RFC3339, "2006-01-02"}
+
+func loadSuppressionFile(requestId string, fileName string) []Suppression {
+	var suppressions []Suppression
+	if len(fileName) == 0 {
+		return suppressions
+	}
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		log.Error(requestId).Err(err).Msgf("Error reading suppression file %s", fileName)
+		return suppressions
+	}
+	if err := json.Unmarshal(content, &suppressions); err != nil {
+		log.Error(requestId).Err(err).Msgf("Error parsing suppression file %s", fileName)
+		return nil
+	}
+	return suppressions
+}
+
+// activeSuppressions drops the entries which match on nothing, have no owner,
+// justification or valid expiry date, or are expired
+func activeSuppressions(requestId string, suppressions []Suppression, now time.Time) []Suppression {
+	var active []Suppression
+	for _, s := range suppressions {
+		if len(s.CveId) == 0 && len(s.Package) == 0 && len(s.ImageRepo) == 0 && len(s.AssetIdentifier) == 0 {
+			log.Warn(requestId).Msgf("Ignoring suppression owned by %s, it does not match on anything", s.Owner)
+			continue
+		}
+		if len(s.Owner) == 0 || len(s.Justification) == 0 {
+			log.Warn(requestId).Msgf("Ignoring suppression %s, owner and justification are required", s.describe())
+			continue
+		}
+		expires, ok := parseExpiry(s.Expires)
+		if !ok {
+			log.Warn(requestId).Msgf("Ignoring suppression %s owned by %s, invalid expiry date %q", s.describe(), s.Owner, s.Expires)
+			continue
+		}
+		if !now.Before(expires) {
+			log.Warn(requestId).Msgf("Ignoring suppression %s owned by %s, expired on %s", s.describe(), s.Owner, s.Expires)
+			continue
+		}
+		active = append(active, s)
+	}
+	return active
+}
+
+func parseExpiry(expires string) (time.Time, bool) {
+	for _, layout := range expiryLayouts {
+		if t, err := time.Parse(layout, expires); err == nil {
+			if layout == "2006-01-02" {
+				// a date is valid for the whole day
+				t = t.AddDate(0, 0, 1)
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (s Suppression) matches(v scan.VulnerabilityDetail, imageRepo string, assetIdentifier string) bool {
+	if len(s.CveId) > 0 && !strings.EqualFold(s.CveId, v.CveId) && !containsFold(v.Aliases, s.CveId) {
+		return false
+	}
+	if len(s.Package) > 0 && s.Package != v.PackageName && s.Package != v.Package {
+		return false
+	}
+	if len(s.ImageRepo) > 0 && !globMatch(s.ImageRepo, imageRepo) && !globMatch(s.ImageRepo, assetIdentifier) {
+		return false
+	}
+	if len(s.AssetIdentifier) > 0 && s.AssetIdentifier != assetIdentifier {
+		return false
+	}
+	return true
+}
+
+func (s Suppression) describe() string {
+	var fields []string
+	for _, field := range []string{s.CveId, s.Package, s.ImageRepo, s.AssetIdentifier} {
+		if len(field) > 0 {
+			fields = append(fields, field)
+		}
+	}
+	return strings.Join(fields, "/")
+}
+
+func globMatch(pattern string, name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+// imageRepository strips the tag or digest from the image name, images looked
+// up by digest only have no repository
+func imageRepository(imageName string) string {
+	if strings.HasPrefix(imageName, "sha256:") {
+		return ""
+	}
+	if i := strings.Index(imageName, "@"); i >= 0 {
+		return imageName[:i]
+	}
+	if i := strings.LastIndex(imageName, ":"); i > strings.LastIndex(imageName, "/") {
+		return imageName[:i]
+	}
+	return imageName
+}
+
+// applySuppressions splits the vulnerabilities into the ones to report and the
+// suppressed ones, the latter are dropped when the action is drop
+func applySuppressions(requestId string, vulnList *[]scan.VulnerabilityDetail, asset *domain.Asset, imageName string, settings AnalysisSettings) ([]scan.VulnerabilityDetail, []scan.VulnerabilityDetail) {
+	suppressions := activeSuppressions(requestId, settings.Suppressions, time.Now())
+	if len(suppressions) == 0 {
+		return *vulnList, nil
+	}
+	imageRepo := imageRepository(imageName)
+	active := []scan.VulnerabilityDetail{}
+	var suppressed []scan.VulnerabilityDetail
+	for _, v := range *vulnList {
+		matched := false
+		for _, s := range suppressions {
+			if s.matches(v, imageRepo, asset.MasterAsset.Identifier) {
+				v.SuppressedBy = s.Owner
+				v.SuppressionJustification = s.Justification
+				v.SuppressionExpires = s.Expires
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			active = append(active, v)
+		} else if settings.SuppressionAction != SuppressionDrop {
+			suppressed = append(suppressed, v)
+		}
+	}
+	log.Info(requestId).Msgf("Suppressed %d of %d vulnerabilities", len(*vulnList)-len(active), len(*vulnList))
+	return active, suppressed
+}
+
+// flagSuppressedEvaluations moves the evaluations into the suppressed category
+// and adds who accepted the risk and why to the details
+func flagSuppressedEvaluations(reqId string, evalMap map[string]*domain.Evaluation) {
+	setCategory(evalMap, SuppressedCategory)
+	for _, eval := range evalMap {
+		appendDetailColumns(reqId, eval, []string{"Suppressed By", "Justification", "Suppression Expires"},
+			[]string{String, String, String}, []string{Summary, Summary, Detail},
+			func(v scan.VulnerabilityDetail) []string {
+				return []string{v.SuppressedBy, v.SuppressionJustification, v.SuppressionExpires}
+			})
+	}
+}
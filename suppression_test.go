@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	domain "github.com/cloudbees-compliance/chplugin-go/v0.4.0/domainv0_4_0"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActiveSuppressions(t *testing.T) {
+	log.Debug().Msg("Inside TestActiveSuppressions - Enter")
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	suppressions := []Suppression{
+		{CveId: "CVE-2022-1304", Owner: "platform", Justification: "not reachable", Expires: "2023-09-01"},
+		{CveId: "CVE-2023-31484", Owner: "platform", Justification: "not reachable", Expires: "2023-08-31"},
+		{CveId: "CVE-2023-31438", Owner: "platform", Justification: "not reachable", Expires: "2023-09-01T11:00:00Z"},
+		{CveId: "CVE-2023-31439", Owner: "platform", Justification: "not reachable"},
+		{CveId: "CVE-2023-31440", Expires: "2024-01-01"},
+		{Owner: "platform", Justification: "not reachable", Expires: "2024-01-01"},
+	}
+	active := activeSuppressions("123", suppressions, now)
+	assert.Equal(t, 1, len(active))
+	assert.Equal(t, "CVE-2022-1304", active[0].CveId)
+	log.Debug().Msg("Inside TestActiveSuppressions - Exit")
+}
+
+func TestSuppressionMatches(t *testing.T) {
+	log.Debug().Msg("Inside TestSuppressionMatches - Enter")
+	v := scan.VulnerabilityDetail{CveId: "CVE-2022-1304", Package: "libcom-err2-1.46.2-2", PackageName: "libcom-err2"}
+	repo := imageRepository("1234567.dkr.ecr.us-east-1.amazonaws.com/test/plugin-test:v1.0.1")
+	assert.Equal(t, "1234567.dkr.ecr.us-east-1.amazonaws.com/test/plugin-test", repo)
+
+	assert.True(t, Suppression{CveId: "cve-2022-1304"}.matches(v, repo, "arn"))
+	assert.True(t, Suppression{CveId: "CVE-2022-1304", Package: "libcom-err2"}.matches(v, repo, "arn"))
+	assert.False(t, Suppression{CveId: "CVE-2022-1304", Package: "openssl"}.matches(v, repo, "arn"))
+	assert.True(t, Suppression{ImageRepo: "*.amazonaws.com/test/*"}.matches(v, repo, "arn"))
+	assert.False(t, Suppression{ImageRepo: "*.amazonaws.com/prod/*"}.matches(v, repo, "arn"))
+	assert.True(t, Suppression{AssetIdentifier: "arn"}.matches(v, repo, "arn"))
+	assert.False(t, Suppression{AssetIdentifier: "other"}.matches(v, repo, "arn"))
+	log.Debug().Msg("Inside TestSuppressionMatches - Exit")
+}
+
+func TestBuildEvaluationsSuppressed(t *testing.T) {
+	log.Debug().Msg("Inside TestBuildEvaluationsSuppressed - Enter")
+	var vulnerabilityList []scan.VulnerabilityDetail
+	vulnerabilitiesByte, _ := os.ReadFile("testdata/getVulnerabilities.json")
+	json.Unmarshal(vulnerabilitiesByte, &vulnerabilityList)
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+	suppressions := []Suppression{{CveId: "CVE-2022-1304", Owner: "platform", Justification: "not reachable", Expires: "2999-01-01"}}
+
+	evalList, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil,
+		AnalysisSettings{EvaluationMode: VulnerabilityMode, SuppressionAction: SuppressionFlag, Suppressions: suppressions})
+	assert.Nil(t, err)
+	var suppressed []*domain.Evaluation
+	for _, eval := range evalList {
+		if *eval.Category == SuppressedCategory {
+			suppressed = append(suppressed, eval)
+		}
+	}
+	assert.Equal(t, 1, len(suppressed))
+	assert.Equal(t, "CVE-2022-1304/"+SuppressedCategory, suppressed[0].Code)
+	assert.Equal(t, len(suppressed[0].DetailHeaders), len(suppressed[0].Failures[0].Details[0].Data))
+	justification := indexOf(suppressed[0].DetailHeaders, "Justification")
+	assert.Equal(t, "not reachable", suppressed[0].Failures[0].Details[0].Data[justification])
+
+	dropped, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil,
+		AnalysisSettings{EvaluationMode: VulnerabilityMode, SuppressionAction: SuppressionDrop, Suppressions: suppressions})
+	assert.Nil(t, err)
+	assert.Equal(t, len(evalList)-1, len(dropped))
+	log.Debug().Msg("Inside TestBuildEvaluationsSuppressed - Exit")
+}
+
+func TestBuildEvaluationsAllSuppressed(t *testing.T) {
+	log.Debug().Msg("Inside TestBuildEvaluationsAllSuppressed - Enter")
+	vulnerabilityList := []scan.VulnerabilityDetail{
+		{CveId: "CVE-2022-1304", Severity: "High", PackageName: "e2fsprogs", PackageVersion: "1.46.5", PackageType: "dpkg", Fix: "None"},
+		{CveId: "CVE-2023-0464", Severity: "Medium", PackageName: "openssl", PackageVersion: "3.0.2", PackageType: "dpkg", Fix: "3.0.9"},
+	}
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+	suppressions := []Suppression{{ImageRepo: "localhost", Owner: "platform", Justification: "base image accepted", Expires: "2999-01-01"}}
+
+	// flagged suppressions are reported, so the scan does not pass
+	flagged, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil,
+		AnalysisSettings{EvaluationMode: VulnerabilityMode, SuppressionAction: SuppressionFlag, Suppressions: suppressions})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(flagged))
+	for _, eval := range flagged {
+		assert.Equal(t, SuppressedCategory, *eval.Category)
+		assert.NotEqual(t, ScanPassedCode, eval.Code)
+	}
+
+	dropped, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil,
+		AnalysisSettings{EvaluationMode: VulnerabilityMode, SuppressionAction: SuppressionDrop, Suppressions: suppressions})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(dropped))
+	assert.Equal(t, ScanPassedCode, dropped[0].Code)
+	assert.Equal(t, "testProfileuuid", dropped[0].Failures[0].ProfileUuid)
+	log.Debug().Msg("Inside TestBuildEvaluationsAllSuppressed - Exit")
+}
+
+func TestBuildEvaluationsPartiallySuppressed(t *testing.T) {
+	log.Debug().Msg("Inside TestBuildEvaluationsPartiallySuppressed - Enter")
+	vulnerabilityList := []scan.VulnerabilityDetail{
+		{CveId: "CVE-2022-1304", Severity: "High", PackageName: "e2fsprogs", PackageVersion: "1.46.5", PackageType: "dpkg", Fix: "None"},
+		{CveId: "CVE-2022-1304", Severity: "High", PackageName: "libcom-err2", PackageVersion: "1.46.5", PackageType: "dpkg", Fix: "None"},
+	}
+	assetProfile := &domain.AssetProfile{Uuid: "testProfileuuid", Identifier: "v1.0.1", Type: "BINARY", AttributesUuid: "testattriuuid"}
+	asset := &domain.Asset{Uuid: "1", MasterAsset: &domain.MasterAsset{Type: "BINARY", SubType: "subtype", Identifier: "localhost"}}
+	suppressions := []Suppression{{CveId: "CVE-2022-1304", Package: "e2fsprogs", Owner: "platform", Justification: "not reachable", Expires: "2999-01-01"}}
+
+	// the active and the suppressed part of the finding keep one evaluation each
+	evalList, err := buildEvaluations("123", &vulnerabilityList, asset, assetProfile, "localhost:v1.0.1", nil,
+		AnalysisSettings{EvaluationMode: VulnerabilityMode, SuppressionAction: SuppressionFlag, Suppressions: suppressions})
+	assert.Nil(t, err)
+	codes := map[string]string{}
+	for _, eval := range evalList {
+		codes[eval.Code] = *eval.Category
+	}
+	assert.Equal(t, len(evalList), len(codes))
+	assert.Equal(t, VulnerabilityCategory, codes["CVE-2022-1304"])
+	assert.Equal(t, SuppressedCategory, codes["CVE-2022-1304/"+SuppressedCategory])
+	log.Debug().Msg("Inside TestBuildEvaluationsPartiallySuppressed - Exit")
+}
+
+func TestLoadSuppressionFile(t *testing.T) {
+	log.Debug().Msg("Inside TestLoadSuppressionFile - Enter")
+	fileName := filepath.Join(t.TempDir(), "suppressions.json")
+	os.WriteFile(fileName, []byte(`[{"cve":"CVE-2022-1304","owner":"platform","justification":"not reachable","expires":"2999-01-01"}]`), 0600)
+	suppressions := loadSuppressionFile("123", fileName)
+	assert.Equal(t, 1, len(suppressions))
+	assert.Equal(t, "platform", suppressions[0].Owner)
+	assert.Empty(t, loadSuppressionFile("123", ""))
+	log.Debug().Msg("Inside TestLoadSuppressionFile - Exit")
+}
+
+func indexOf(list []string, value string) int {
+	for i, item := range list {
+		if item == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,6 +1,7 @@
 package testdata
 
 import (
+	"context"
 	"errors"
 	"os"
 
@@ -9,31 +10,31 @@ import (
 
 const ErrorResponse = "Error occured"
 
-var GetImageMock func(requestId string, imageName string) ([]byte, error)
-var GetRegistriesMock func(requestId string) ([]byte, error)
-var GetSystemStatusMock func(requestId string) ([]byte, error)
-var GetVulnerabilitiesMock func(requestId string, imageName string) ([]byte, error)
+var GetImageMock func(ctx context.Context, requestId string, imageName string) ([]byte, error)
+var GetRegistriesMock func(ctx context.Context, requestId string) ([]byte, error)
+var GetSystemStatusMock func(ctx context.Context, requestId string) ([]byte, error)
+var GetVulnerabilitiesMock func(ctx context.Context, requestId string, imageName string, baseImage bool) ([]byte, error)
 
 type HttpMock1 struct{}
 
-func (u HttpMock1) GetImage(requestId string, imageName string) ([]byte, error) {
-	return GetImageMock(requestId, imageName)
+func (u HttpMock1) GetImage(ctx context.Context, requestId string, imageName string) ([]byte, error) {
+	return GetImageMock(ctx, requestId, imageName)
 }
 
-func (u HttpMock1) GetRegistries(requestId string) ([]byte, error) {
-	return GetRegistriesMock(requestId)
+func (u HttpMock1) GetRegistries(ctx context.Context, requestId string) ([]byte, error) {
+	return GetRegistriesMock(ctx, requestId)
 }
 
-func (u HttpMock1) GetSystemStatus(requestId string) ([]byte, error) {
-	return GetSystemStatusMock(requestId)
+func (u HttpMock1) GetSystemStatus(ctx context.Context, requestId string) ([]byte, error) {
+	return GetSystemStatusMock(ctx, requestId)
 }
 
-func (u HttpMock1) GetVulnerabilities(requestId string, imageName string) ([]byte, error) {
-	return GetVulnerabilitiesMock(requestId, imageName)
+func (u HttpMock1) GetVulnerabilities(ctx context.Context, requestId string, imageName string, baseImage bool) ([]byte, error) {
+	return GetVulnerabilitiesMock(ctx, requestId, imageName, baseImage)
 }
 
 func MockGetImage(jsonpath string) {
-	GetImageMock = func(requestId string, imageName string) ([]byte, error) {
+	GetImageMock = func(ctx context.Context, requestId string, imageName string) ([]byte, error) {
 		file, err := os.ReadFile(jsonpath)
 		if err != nil {
 			log.Error().Err(err).Msg("Error reading test data")
@@ -43,13 +44,13 @@ func MockGetImage(jsonpath string) {
 }
 
 func MockGetImageError() {
-	GetImageMock = func(requestId string, imageName string) ([]byte, error) {
+	GetImageMock = func(ctx context.Context, requestId string, imageName string) ([]byte, error) {
 		return []byte(ErrorResponse), errors.New("error when getting image")
 	}
 }
 
 func MockGetRegistries(jsonpath string) {
-	GetRegistriesMock = func(requestId string) ([]byte, error) {
+	GetRegistriesMock = func(ctx context.Context, requestId string) ([]byte, error) {
 		file, err := os.ReadFile(jsonpath)
 		if err != nil {
 			log.Error().Err(err).Msg("Error reading test data")
@@ -59,19 +60,19 @@ func MockGetRegistries(jsonpath string) {
 }
 
 func MockGetRegistriesError() {
-	GetRegistriesMock = func(requestId string) ([]byte, error) {
+	GetRegistriesMock = func(ctx context.Context, requestId string) ([]byte, error) {
 		return []byte(ErrorResponse), errors.New("error when getting registries")
 	}
 }
 
 func MockGetRegistriesJsonError() {
-	GetRegistriesMock = func(requestId string) ([]byte, error) {
+	GetRegistriesMock = func(ctx context.Context, requestId string) ([]byte, error) {
 		return []byte(ErrorResponse), nil
 	}
 }
 
 func MockGetVulnerabilities(jsonPath string) {
-	GetVulnerabilitiesMock = func(requestId string, imageName string) ([]byte, error) {
+	GetVulnerabilitiesMock = func(ctx context.Context, requestId string, imageName string, baseImage bool) ([]byte, error) {
 		file, err := os.ReadFile(jsonPath)
 		if err != nil {
 			log.Error().Err(err).Msg("Error reading test data")
@@ -81,25 +82,25 @@ func MockGetVulnerabilities(jsonPath string) {
 }
 
 func MockGetEmptyVulnerabilities() {
-	GetVulnerabilitiesMock = func(requestId string, imageName string) ([]byte, error) {
+	GetVulnerabilitiesMock = func(ctx context.Context, requestId string, imageName string, baseImage bool) ([]byte, error) {
 		return []byte("[]"), nil
 	}
 }
 
 func MockGetVulnerabilitiesError() {
-	GetVulnerabilitiesMock = func(requestId string, imageName string) ([]byte, error) {
+	GetVulnerabilitiesMock = func(ctx context.Context, requestId string, imageName string, baseImage bool) ([]byte, error) {
 		return []byte(ErrorResponse), errors.New("error when getting vulnerabilities")
 	}
 }
 
 func MockGetVulnerabilitiesJsonError() {
-	GetVulnerabilitiesMock = func(requestId string, imageName string) ([]byte, error) {
+	GetVulnerabilitiesMock = func(ctx context.Context, requestId string, imageName string, baseImage bool) ([]byte, error) {
 		return []byte(ErrorResponse), nil
 	}
 }
 
 func MockGetSystemStatus(jsonPath string) {
-	GetSystemStatusMock = func(requestId string) ([]byte, error) {
+	GetSystemStatusMock = func(ctx context.Context, requestId string) ([]byte, error) {
 		file, err := os.ReadFile(jsonPath)
 		if err != nil {
 			log.Error().Err(err).Msg("Error reading test data")
@@ -110,7 +111,7 @@ func MockGetSystemStatus(jsonPath string) {
 }
 
 func MockGetSystemStatusError() {
-	GetSystemStatusMock = func(requestId string) ([]byte, error) {
+	GetSystemStatusMock = func(ctx context.Context, requestId string) ([]byte, error) {
 		return []byte(ErrorResponse), errors.New("error when getting system status")
 	}
 }
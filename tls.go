@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// certReloader serves the TLS configuration of the gRPC listener and reloads
+// the certificate, key and client CA bundle when one of the files changes
+type certReloader struct {
+	certFile, keyFile, caFile string
+
+	mu       sync.Mutex
+	modTimes [3]time.Time
+	config   *tls.Config
+}
+
+func newCertReloader(certFile, keyFile, caFile string) (*certReloader, error) {
+	if len(certFile) == 0 || len(keyFile) == 0 {
+		return nil, errors.New("server.tls.cert and server.tls.key are both required")
+	}
+	r := &certReloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if _, err := r.current(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// current returns the TLS configuration, reloading it when the files changed.
+// A failed reload keeps serving the previous configuration.
+func (r *certReloader) current() (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	modTimes, err := r.stat()
+	if err != nil {
+		if r.config != nil {
+			log.Warn().Err(err).Msg("TLS files not readable, keeping the loaded certificate")
+			return r.config, nil
+		}
+		return nil, err
+	}
+	if r.config != nil && modTimes == r.modTimes {
+		return r.config, nil
+	}
+	tlsConfig, err := r.load()
+	if err != nil {
+		if r.config != nil {
+			log.Warn().Err(err).Msg("TLS reload failed, keeping the loaded certificate")
+			return r.config, nil
+		}
+		return nil, err
+	}
+	if r.config != nil {
+		log.Info().Msgf("TLS certificate reloaded from %s", r.certFile)
+	}
+	r.config, r.modTimes = tlsConfig, modTimes
+	return r.config, nil
+}
+
+func (r *certReloader) stat() ([3]time.Time, error) {
+	var modTimes [3]time.Time
+	for i, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if len(file) == 0 {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+func (r *certReloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS certificate: %w", err)
+	}
+	// the config replaces the one grpc built, so it has to offer h2 over ALPN
+	// like grpc does or clients which require ALPN refuse the connection
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2"},
+	}
+	if len(r.caFile) > 0 {
+		caBundle, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read client CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificate found in client CA bundle %s", r.caFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+func (r *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current()
+		},
+	}
+}
+
+// tlsServerOptions returns the credentials of the gRPC server, none when TLS
+// is not configured
+func tlsServerOptions() []grpc.ServerOption {
+	certFile := config.Config.GetString("server.tls.cert")
+	keyFile := config.Config.GetString("server.tls.key")
+	caFile := config.Config.GetString("server.tls.clientca")
+	if len(certFile) == 0 && len(keyFile) == 0 {
+		log.Warn().Msg("TLS not configured, serving plaintext gRPC")
+		return nil
+	}
+	reloader, err := newCertReloader(certFile, keyFile, caFile)
+	if err != nil {
+		log.Panic().Err(err).Msg("failed to configure TLS")
+	}
+	if len(caFile) > 0 {
+		log.Info().Msg("Serving gRPC with mutual TLS")
+	} else {
+		log.Info().Msg("Serving gRPC with TLS")
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(reloader.tlsConfig()))}
+}
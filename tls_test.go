@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// writeCert writes a self signed certificate and its key, the certificate can
+// sign other certificates so it doubles as a CA
+func writeCert(t *testing.T, dir, name string, serial int64) (string, string, tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	assert.Nil(t, os.WriteFile(certFile, certPem, 0600))
+	assert.Nil(t, os.WriteFile(keyFile, keyPem, 0600))
+	cert, err := tls.X509KeyPair(certPem, keyPem)
+	assert.Nil(t, err)
+	return certFile, keyFile, cert
+}
+
+// callGrpc makes a health check call to a gRPC server serving the reloader
+// credentials and returns the server certificate, the call fails when the
+// server does not negotiate h2 over ALPN
+func callGrpc(t *testing.T, reloader *certReloader, clientConfig *tls.Config) (*x509.Certificate, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(reloader.tlsConfig())))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+
+	var peer *x509.Certificate
+	clientConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		if state.NegotiatedProtocol != "h2" {
+			return fmt.Errorf("negotiated protocol %q instead of h2", state.NegotiatedProtocol)
+		}
+		peer = state.PeerCertificates[0]
+		return nil
+	}
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(clientConfig)))
+	assert.Nil(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return nil, err
+	}
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+	return peer, nil
+}
+
+func TestCertReloader(t *testing.T) {
+	log.Debug().Msg("Inside TestCertReloader - Enter")
+	dir := t.TempDir()
+	certFile, keyFile, serverCert := writeCert(t, dir, "server", 1)
+	reloader, err := newCertReloader(certFile, keyFile, "")
+	assert.Nil(t, err)
+
+	roots := x509.NewCertPool()
+	leaf, _ := x509.ParseCertificate(serverCert.Certificate[0])
+	roots.AddCert(leaf)
+	peer, err := callGrpc(t, reloader, &tls.Config{RootCAs: roots, ServerName: "localhost"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), peer.SerialNumber.Int64())
+
+	// rotate the certificate, the next handshake serves the new one
+	_, _, rotated := writeCert(t, dir, "server", 2)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	leaf, _ = x509.ParseCertificate(rotated.Certificate[0])
+	roots.AddCert(leaf)
+	peer, err = callGrpc(t, reloader, &tls.Config{RootCAs: roots, ServerName: "localhost"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), peer.SerialNumber.Int64())
+
+	// a broken file keeps the loaded certificate
+	os.WriteFile(keyFile, []byte("broken"), 0600)
+	later := future.Add(time.Minute)
+	os.Chtimes(keyFile, later, later)
+	current, err := reloader.current()
+	assert.Nil(t, err)
+	assert.Equal(t, rotated.Certificate[0], current.Certificates[0].Certificate[0])
+
+	_, err = newCertReloader(certFile, "", "")
+	assert.NotNil(t, err)
+	_, err = newCertReloader(certFile, keyFile, "")
+	assert.NotNil(t, err)
+	log.Debug().Msg("Inside TestCertReloader - Exit")
+}
+
+func TestCertReloaderMutualTls(t *testing.T) {
+	log.Debug().Msg("Inside TestCertReloaderMutualTls - Enter")
+	dir := t.TempDir()
+	certFile, keyFile, serverCert := writeCert(t, dir, "server", 1)
+	caFile, _, clientCert := writeCert(t, dir, "client", 3)
+	_, _, otherCert := writeCert(t, dir, "other", 4)
+	reloader, err := newCertReloader(certFile, keyFile, caFile)
+	assert.Nil(t, err)
+
+	roots := x509.NewCertPool()
+	leaf, _ := x509.ParseCertificate(serverCert.Certificate[0])
+	roots.AddCert(leaf)
+	_, err = callGrpc(t, reloader, &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: []tls.Certificate{clientCert}})
+	assert.Nil(t, err)
+	_, err = callGrpc(t, reloader, &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: []tls.Certificate{otherCert}})
+	assert.NotNil(t, err)
+	_, err = callGrpc(t, reloader, &tls.Config{RootCAs: roots, ServerName: "localhost"})
+	assert.NotNil(t, err)
+
+	os.WriteFile(caFile, []byte("no certificates"), 0600)
+	_, err = newCertReloader(certFile, keyFile, caFile)
+	assert.NotNil(t, err)
+	log.Debug().Msg("Inside TestCertReloaderMutualTls - Exit")
+}
@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TracerName = "github.com/cloudbees-compliance/compliance-hub-plugin-anchore"
+
+// span attribute keys
+const (
+	AssetSubType       = "asset.subtype"
+	AssetIdentifier    = "asset.identifier"
+	ProfileIdentifier  = "profile.identifier"
+	ImageReference     = "image.reference"
+	ImageState         = "image.state"
+	AssetCount         = "asset.count"
+	VulnerabilityCount = "vulnerability.count"
+	EvaluationCount    = "evaluation.count"
+	RegistryCount      = "registry.count"
+	RetryAttempt       = "retry.attempt"
+)
+
+// Start starts a span with the plugin tracer of the globally registered
+// provider, the same one the otelgrpc interceptors use
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End records the error, if any, on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
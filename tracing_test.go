@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/cloudbees-compliance/chlog-go/log"
+	scan "github.com/cloudbees-compliance/compliance-hub-plugin-anchore/scan"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/testdata"
+	"github.com/cloudbees-compliance/compliance-hub-plugin-anchore/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func spansNamed(recorder *tracetest.SpanRecorder, name string) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) (string, bool) {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return attr.Value.Emit(), true
+		}
+	}
+	return "", false
+}
+
+func TestExecuteAnalyserSpans(t *testing.T) {
+	log.Debug().Msg("TestExecuteAnalyserSpans - Enter")
+	recorder := recordSpans(t)
+	anchore := NewAnchoreScanner()
+	req := mockEcrExecuteRequest()
+	fetcher := &PluginFetcher{}
+
+	testdata.MockGetSystemStatus("testdata/getsystemstatus.json")
+	testdata.MockGetRegistries("testdata/getregistries.json")
+	testdata.MockGetImage("testdata/getimage.json")
+	testdata.MockGetVulnerabilities("testdata/getVulnerabilities.json")
+	scan.IAnchore = testdata.HttpMock1{}
+
+	res, err := anchore.ExecuteAnalyser(context.Background(), req, fetcher, nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, res)
+
+	fetch := spansNamed(recorder, "FetchAssets")
+	assert.Len(t, fetch, 1)
+	count, _ := spanAttribute(fetch[0], tracing.AssetCount)
+	assert.NotEqual(t, "0", count)
+
+	assets := spansNamed(recorder, "ProcessAsset")
+	assert.NotEmpty(t, assets)
+	for _, asset := range assets {
+		_, ok := spanAttribute(asset, tracing.AssetSubType)
+		assert.True(t, ok)
+		_, ok = spanAttribute(asset, tracing.VulnerabilityCount)
+		assert.True(t, ok)
+	}
+
+	resolves := spansNamed(recorder, "ResolveImage")
+	assert.Len(t, resolves, len(assets))
+	for _, resolve := range resolves {
+		state, _ := spanAttribute(resolve, tracing.ImageState)
+		assert.Equal(t, scan.StateAnalyzed, state)
+		// the anchore calls are children of the name resolution
+		for _, call := range spansNamed(recorder, "anchore.GetImage") {
+			if call.Parent().SpanID() == resolve.SpanContext().SpanID() {
+				reference, _ := spanAttribute(call, tracing.ImageReference)
+				imageName, _ := spanAttribute(resolve, tracing.ImageReference)
+				assert.Equal(t, imageName, reference)
+			}
+		}
+	}
+	assert.NotEmpty(t, spansNamed(recorder, "anchore.GetRegistries"))
+	for _, call := range spansNamed(recorder, "anchore.GetVulnerabilities") {
+		_, ok := spanAttribute(call, tracing.VulnerabilityCount)
+		assert.True(t, ok)
+	}
+	log.Debug().Msg("TestExecuteAnalyserSpans - Exit")
+}
+
+func TestExecuteAnalyserSpansError(t *testing.T) {
+	log.Debug().Msg("TestExecuteAnalyserSpansError - Enter")
+	recorder := recordSpans(t)
+	anchore := NewAnchoreScanner()
+	req := mockEcrExecuteRequest()
+	fetcher := &PluginFetcher{}
+
+	testdata.MockGetSystemStatus("testdata/getsystemstatus.json")
+	testdata.MockGetRegistries("testdata/getregistries.json")
+	testdata.MockGetImage("testdata/getimage.json")
+	testdata.MockGetVulnerabilitiesError()
+	scan.IAnchore = testdata.HttpMock1{}
+
+	_, err := anchore.ExecuteAnalyser(context.Background(), req, fetcher, nil)
+	assert.NotNil(t, err)
+
+	for _, name := range []string{"anchore.GetVulnerabilities", "ProcessAsset"} {
+		spans := spansNamed(recorder, name)
+		if !assert.Len(t, spans, 1) {
+			continue
+		}
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.NotEmpty(t, spans[0].Events())
+	}
+	log.Debug().Msg("TestExecuteAnalyserSpansError - Exit")
+}
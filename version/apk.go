@@ -0,0 +1,102 @@
+package version
+
+import (
+	"strings"
+)
+
+// apkSuffixes is the alpine suffix ordering, a version without suffix sorts
+// between _rc and _cvs
+var apkSuffixes = map[string]int{
+	"alpha": 0,
+	"beta":  1,
+	"pre":   2,
+	"rc":    3,
+	"":      4,
+	"cvs":   5,
+	"svn":   6,
+	"git":   7,
+	"hg":    8,
+	"p":     9,
+}
+
+type apkSuffix struct {
+	rank   int
+	number string
+}
+
+type apkVersion struct {
+	numbers  []string
+	letter   string
+	suffixes []apkSuffix
+	revision string
+}
+
+// compareApk implements the alpine version ordering
+// (number{.number}[letter]{_suffix[number]}[~hash][-rrevision])
+func compareApk(a string, b string) int {
+	versionA := parseApk(a)
+	versionB := parseApk(b)
+
+	for i := 0; i < len(versionA.numbers) || i < len(versionB.numbers); i++ {
+		if i >= len(versionA.numbers) {
+			return -1
+		}
+		if i >= len(versionB.numbers) {
+			return 1
+		}
+		if result := compareNumeric(versionA.numbers[i], versionB.numbers[i]); result != 0 {
+			return result
+		}
+	}
+	if result := strings.Compare(versionA.letter, versionB.letter); result != 0 {
+		return result
+	}
+	noSuffix := apkSuffix{rank: apkSuffixes[""]}
+	for i := 0; i < len(versionA.suffixes) || i < len(versionB.suffixes); i++ {
+		suffixA, suffixB := noSuffix, noSuffix
+		if i < len(versionA.suffixes) {
+			suffixA = versionA.suffixes[i]
+		}
+		if i < len(versionB.suffixes) {
+			suffixB = versionB.suffixes[i]
+		}
+		if suffixA.rank != suffixB.rank {
+			return suffixA.rank - suffixB.rank
+		}
+		if result := compareNumeric(suffixA.number, suffixB.number); result != 0 {
+			return result
+		}
+	}
+	return compareNumeric(versionA.revision, versionB.revision)
+}
+
+func parseApk(v string) apkVersion {
+	var parsed apkVersion
+	if i := strings.LastIndex(v, "-r"); i >= 0 {
+		parsed.revision = v[i+2:]
+		v = v[:i]
+	}
+	if i := strings.Index(v, "~"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, "_")
+	release := parts[0]
+	if len(release) > 0 && isAlpha(release[len(release)-1]) {
+		parsed.letter = release[len(release)-1:]
+		release = release[:len(release)-1]
+	}
+	parsed.numbers = strings.Split(release, ".")
+	for _, part := range parts[1:] {
+		i := 0
+		for i < len(part) && isAlpha(part[i]) {
+			i++
+		}
+		rank, ok := apkSuffixes[part[:i]]
+		if !ok {
+			// unknown suffixes are treated as pre-releases
+			rank = apkSuffixes["alpha"]
+		}
+		parsed.suffixes = append(parsed.suffixes, apkSuffix{rank: rank, number: part[i:]})
+	}
+	return parsed
+}
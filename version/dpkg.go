@@ -0,0 +1,91 @@
+package version
+
+import (
+	"strings"
+)
+
+// compareDpkg implements the debian version ordering ([epoch:]upstream[-revision])
+func compareDpkg(a string, b string) int {
+	epochA, upstreamA, revisionA := splitDpkg(a)
+	epochB, upstreamB, revisionB := splitDpkg(b)
+	if result := compareNumeric(epochA, epochB); result != 0 {
+		return result
+	}
+	if result := verrevcmp(upstreamA, upstreamB); result != 0 {
+		return result
+	}
+	return verrevcmp(revisionA, revisionB)
+}
+
+func splitDpkg(v string) (string, string, string) {
+	epoch := "0"
+	if i := strings.Index(v, ":"); i >= 0 {
+		epoch = v[:i]
+		v = v[i+1:]
+	}
+	revision := ""
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		revision = v[i+1:]
+		v = v[:i]
+	}
+	return epoch, v, revision
+}
+
+// dpkgOrder gives the weight of a non digit character: '~' sorts before
+// everything, letters before non letters
+func dpkgOrder(c byte) int {
+	switch {
+	case isDigit(c):
+		return 0
+	case isAlpha(c):
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+func verrevcmp(a string, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		firstDiff := 0
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := 0, 0
+			if i < len(a) {
+				ac = dpkgOrder(a[i])
+			}
+			if j < len(b) {
+				bc = dpkgOrder(b[j])
+			}
+			if ac != bc {
+				return ac - bc
+			}
+			i++
+			j++
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		for i < len(a) && j < len(b) && isDigit(a[i]) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
@@ -0,0 +1,190 @@
+package version
+
+import (
+	"strings"
+)
+
+// mavenQualifiers is the ordering of the well known maven qualifiers, unknown
+// qualifiers sort after all of them
+var mavenQualifiers = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+
+var mavenAliases = map[string]string{
+	"ga":      "",
+	"final":   "",
+	"release": "",
+	"cr":      "rc",
+}
+
+const mavenIntItem = 0
+const mavenStringItem = 1
+const mavenListItem = 2
+
+// mavenItem is one element of a parsed maven version, following the
+// ComparableVersion model of maven-artifact
+type mavenItem struct {
+	kind  int
+	value string
+	items []*mavenItem
+}
+
+// compareMaven implements the maven ComparableVersion ordering
+func compareMaven(a string, b string) int {
+	return parseMaven(a).compare(parseMaven(b))
+}
+
+func parseMaven(v string) *mavenItem {
+	v = strings.ToLower(v)
+	list := &mavenItem{kind: mavenListItem}
+	stack := []*mavenItem{list}
+	digit := false
+	start := 0
+
+	pushList := func() {
+		sublist := &mavenItem{kind: mavenListItem}
+		list.items = append(list.items, sublist)
+		list = sublist
+		stack = append(stack, list)
+	}
+
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c == '.':
+			list.items = append(list.items, newMavenItem(digit, v[start:i], false))
+			start = i + 1
+		case c == '-':
+			list.items = append(list.items, newMavenItem(digit, v[start:i], false))
+			start = i + 1
+			pushList()
+		case isDigit(c):
+			if !digit && i > start {
+				list.items = append(list.items, newMavenItem(false, v[start:i], true))
+				start = i
+				pushList()
+			}
+			digit = true
+		default:
+			if digit && i > start {
+				list.items = append(list.items, newMavenItem(true, v[start:i], false))
+				start = i
+				pushList()
+			}
+			digit = false
+		}
+	}
+	if len(v) > start {
+		list.items = append(list.items, newMavenItem(digit, v[start:], false))
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		stack[i].normalize()
+	}
+	return stack[0]
+}
+
+func newMavenItem(digit bool, value string, followedByDigit bool) *mavenItem {
+	if len(value) == 0 {
+		return &mavenItem{kind: mavenIntItem}
+	}
+	if digit {
+		return &mavenItem{kind: mavenIntItem, value: strings.TrimLeft(value, "0")}
+	}
+	if followedByDigit && len(value) == 1 {
+		switch value {
+		case "a":
+			value = "alpha"
+		case "b":
+			value = "beta"
+		case "m":
+			value = "milestone"
+		}
+	}
+	if alias, ok := mavenAliases[value]; ok {
+		value = alias
+	}
+	return &mavenItem{kind: mavenStringItem, value: value}
+}
+
+func (m *mavenItem) isNull() bool {
+	switch m.kind {
+	case mavenIntItem:
+		return len(m.value) == 0
+	case mavenStringItem:
+		return len(m.value) == 0
+	default:
+		return len(m.items) == 0
+	}
+}
+
+// normalize strips the trailing null items (0, "", final, ga) of the list
+func (m *mavenItem) normalize() {
+	for i := len(m.items) - 1; i >= 0; i-- {
+		item := m.items[i]
+		if item.isNull() {
+			m.items = append(m.items[:i], m.items[i+1:]...)
+		} else if item.kind != mavenListItem {
+			break
+		}
+	}
+}
+
+// compare compares the item with other, a nil other stands for a missing item
+func (m *mavenItem) compare(other *mavenItem) int {
+	switch m.kind {
+	case mavenIntItem:
+		if other == nil {
+			return compareBool(len(m.value) > 0, false)
+		}
+		if other.kind == mavenIntItem {
+			return compareNumeric(m.value, other.value)
+		}
+		return 1
+	case mavenStringItem:
+		if other == nil {
+			return strings.Compare(mavenQualifier(m.value), mavenQualifier(""))
+		}
+		switch other.kind {
+		case mavenIntItem, mavenListItem:
+			return -1
+		default:
+			return strings.Compare(mavenQualifier(m.value), mavenQualifier(other.value))
+		}
+	default:
+		if other == nil {
+			if len(m.items) == 0 {
+				return 0
+			}
+			return m.items[0].compare(nil)
+		}
+		switch other.kind {
+		case mavenIntItem:
+			return -1
+		case mavenStringItem:
+			return 1
+		}
+		for i := 0; i < len(m.items) || i < len(other.items); i++ {
+			var result int
+			switch {
+			case i >= len(m.items):
+				result = -other.items[i].compare(nil)
+			case i >= len(other.items):
+				result = m.items[i].compare(nil)
+			default:
+				result = m.items[i].compare(other.items[i])
+			}
+			if result != 0 {
+				return result
+			}
+		}
+		return 0
+	}
+}
+
+// mavenQualifier returns a key which sorts the qualifiers in maven order
+func mavenQualifier(value string) string {
+	for i, qualifier := range mavenQualifiers {
+		if qualifier == value {
+			return string(rune('0' + i))
+		}
+	}
+	return string(rune('0'+len(mavenQualifiers))) + "-" + value
+}
@@ -0,0 +1,137 @@
+package version
+
+import (
+	"regexp"
+	"strings"
+)
+
+var pep440Pattern = regexp.MustCompile(`^v?(?:(\d+)!)?(\d+(?:\.\d+)*)` +
+	`(?:[-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?(\d+)?)?` +
+	`(?:-(\d+)|[-_.]?(post|rev|r)[-_.]?(\d+)?)?` +
+	`(?:[-_.]?(dev)[-_.]?(\d+)?)?` +
+	`(?:\+([a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+var pep440Phases = map[string]int{
+	"a":       0,
+	"alpha":   0,
+	"b":       1,
+	"beta":    1,
+	"c":       2,
+	"rc":      2,
+	"pre":     2,
+	"preview": 2,
+}
+
+// pre-release ranks used for versions without a pre-release segment
+const pep440DevRelease = -1
+const pep440FinalRelease = 3
+
+type pep440Version struct {
+	epoch     string
+	release   []string
+	preRank   int
+	preNumber string
+	hasPost   bool
+	post      string
+	hasDev    bool
+	dev       string
+	local     []string
+}
+
+// comparePep440 implements the python version ordering, versions that are not
+// valid PEP 440 versions fall back to the generic ordering
+func comparePep440(a string, b string) int {
+	versionA, okA := parsePep440(a)
+	versionB, okB := parsePep440(b)
+	if !okA || !okB {
+		return rpmvercmp(a, b)
+	}
+	if result := compareNumeric(versionA.epoch, versionB.epoch); result != 0 {
+		return result
+	}
+	for i := 0; i < len(versionA.release) || i < len(versionB.release); i++ {
+		partA, partB := "0", "0"
+		if i < len(versionA.release) {
+			partA = versionA.release[i]
+		}
+		if i < len(versionB.release) {
+			partB = versionB.release[i]
+		}
+		if result := compareNumeric(partA, partB); result != 0 {
+			return result
+		}
+	}
+	if versionA.preRank != versionB.preRank {
+		return versionA.preRank - versionB.preRank
+	}
+	if result := compareNumeric(versionA.preNumber, versionB.preNumber); result != 0 {
+		return result
+	}
+	if versionA.hasPost != versionB.hasPost {
+		return compareBool(versionA.hasPost, versionB.hasPost)
+	}
+	if result := compareNumeric(versionA.post, versionB.post); result != 0 {
+		return result
+	}
+	// a development release sorts before the release it precedes
+	if versionA.hasDev != versionB.hasDev {
+		return compareBool(versionB.hasDev, versionA.hasDev)
+	}
+	if result := compareNumeric(versionA.dev, versionB.dev); result != 0 {
+		return result
+	}
+	for i := 0; i < len(versionA.local) && i < len(versionB.local); i++ {
+		if result := compareLocalSegment(versionA.local[i], versionB.local[i]); result != 0 {
+			return result
+		}
+	}
+	return len(versionA.local) - len(versionB.local)
+}
+
+func parsePep440(v string) (pep440Version, bool) {
+	match := pep440Pattern.FindStringSubmatch(strings.ToLower(v))
+	if match == nil {
+		return pep440Version{}, false
+	}
+	parsed := pep440Version{
+		epoch:     match[1],
+		release:   strings.Split(match[2], "."),
+		preRank:   pep440FinalRelease,
+		preNumber: match[4],
+		hasPost:   len(match[5]) > 0 || len(match[6]) > 0,
+		post:      match[5] + match[7],
+		hasDev:    len(match[8]) > 0,
+		dev:       match[9],
+	}
+	if len(match[3]) > 0 {
+		parsed.preRank = pep440Phases[match[3]]
+	} else if !parsed.hasPost && parsed.hasDev {
+		parsed.preRank = pep440DevRelease
+	}
+	if len(match[10]) > 0 {
+		parsed.local = strings.FieldsFunc(match[10], func(r rune) bool {
+			return r == '-' || r == '_' || r == '.'
+		})
+	}
+	return parsed, true
+}
+
+// compareLocalSegment orders numeric local segments after alphanumeric ones
+func compareLocalSegment(a string, b string) int {
+	numericA := isNumeric(a)
+	numericB := isNumeric(b)
+	if numericA != numericB {
+		return compareBool(numericA, numericB)
+	}
+	return compareIdentifier(a, b)
+}
+
+func compareBool(a bool, b bool) int {
+	if a == b {
+		return 0
+	}
+	if a {
+		return 1
+	}
+	return -1
+}
@@ -0,0 +1,133 @@
+package version
+
+import (
+	"strings"
+)
+
+// compareRpm implements the rpm version ordering ([epoch:]version[-release])
+func compareRpm(a string, b string) int {
+	epochA, versionA, releaseA := splitRpm(a)
+	epochB, versionB, releaseB := splitRpm(b)
+	if result := compareNumeric(epochA, epochB); result != 0 {
+		return result
+	}
+	if result := rpmvercmp(versionA, versionB); result != 0 {
+		return result
+	}
+	// a missing release matches any release
+	if len(releaseA) == 0 || len(releaseB) == 0 {
+		return 0
+	}
+	return rpmvercmp(releaseA, releaseB)
+}
+
+func splitRpm(v string) (string, string, string) {
+	epoch := "0"
+	if i := strings.Index(v, ":"); i >= 0 {
+		epoch = v[:i]
+		v = v[i+1:]
+	}
+	release := ""
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		release = v[i+1:]
+		v = v[:i]
+	}
+	return epoch, v, release
+}
+
+func isRpmSeparator(c byte) bool {
+	return !isDigit(c) && !isAlpha(c) && c != '~' && c != '^'
+}
+
+// rpmvercmp is a port of the rpmvercmp segment comparison from rpmio
+func rpmvercmp(a string, b string) int {
+	if a == b {
+		return 0
+	}
+	for len(a) > 0 || len(b) > 0 {
+		for len(a) > 0 && isRpmSeparator(a[0]) {
+			a = a[1:]
+		}
+		for len(b) > 0 && isRpmSeparator(b[0]) {
+			b = b[1:]
+		}
+
+		// tilde sorts before everything else, including the end of the string
+		if (len(a) > 0 && a[0] == '~') || (len(b) > 0 && b[0] == '~') {
+			if len(a) == 0 || a[0] != '~' {
+				return 1
+			}
+			if len(b) == 0 || b[0] != '~' {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		// caret sorts after the end of the string but before anything else
+		if (len(a) > 0 && a[0] == '^') || (len(b) > 0 && b[0] == '^') {
+			if len(a) == 0 {
+				return -1
+			}
+			if len(b) == 0 {
+				return 1
+			}
+			if a[0] != '^' {
+				return 1
+			}
+			if b[0] != '^' {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if len(a) == 0 || len(b) == 0 {
+			break
+		}
+
+		isNumeric := isDigit(a[0])
+		segmentA := takeSegment(a, isNumeric)
+		segmentB := takeSegment(b, isNumeric)
+		a, b = a[len(segmentA):], b[len(segmentB):]
+
+		// segments of different kinds, numeric is newer
+		if len(segmentB) == 0 {
+			if isNumeric {
+				return 1
+			}
+			return -1
+		}
+
+		var result int
+		if isNumeric {
+			result = compareNumeric(segmentA, segmentB)
+		} else {
+			result = strings.Compare(segmentA, segmentB)
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	if len(a) == 0 {
+		return -1
+	}
+	return 1
+}
+
+func takeSegment(v string, numeric bool) string {
+	i := 0
+	for i < len(v) {
+		if numeric && !isDigit(v[i]) {
+			break
+		}
+		if !numeric && !isAlpha(v[i]) {
+			break
+		}
+		i++
+	}
+	return v[:i]
+}
@@ -0,0 +1,82 @@
+package version
+
+import (
+	"strings"
+)
+
+// compareSemver implements semantic version ordering as used by npm and go
+// modules, the build metadata is ignored
+func compareSemver(a string, b string) int {
+	coreA, preA := splitSemver(a)
+	coreB, preB := splitSemver(b)
+
+	partsA := strings.Split(coreA, ".")
+	partsB := strings.Split(coreB, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		partA, partB := "0", "0"
+		if i < len(partsA) {
+			partA = partsA[i]
+		}
+		if i < len(partsB) {
+			partB = partsB[i]
+		}
+		if result := compareIdentifier(partA, partB); result != 0 {
+			return result
+		}
+	}
+
+	// a version without pre-release has a higher precedence
+	if len(preA) == 0 || len(preB) == 0 {
+		return len(preB) - len(preA)
+	}
+	identifiersA := strings.Split(preA, ".")
+	identifiersB := strings.Split(preB, ".")
+	for i := 0; i < len(identifiersA) && i < len(identifiersB); i++ {
+		if result := compareIdentifier(identifiersA[i], identifiersB[i]); result != 0 {
+			return result
+		}
+	}
+	return len(identifiersA) - len(identifiersB)
+}
+
+func splitSemver(v string) (string, string) {
+	v = strings.TrimPrefix(strings.TrimPrefix(v, "v"), "=")
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+	pre := ""
+	if i := strings.Index(v, "-"); i >= 0 {
+		pre = v[i+1:]
+		v = v[:i]
+	}
+	return v, pre
+}
+
+// compareIdentifier compares numeric identifiers numerically, they sort
+// before alphanumeric identifiers which are compared lexically
+func compareIdentifier(a string, b string) int {
+	numericA := isNumeric(a)
+	numericB := isNumeric(b)
+	switch {
+	case numericA && numericB:
+		return compareNumeric(a, b)
+	case numericA:
+		return -1
+	case numericB:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func isNumeric(v string) bool {
+	if len(v) == 0 {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		if !isDigit(v[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package version
+
+import (
+	"strings"
+)
+
+const Dpkg = "dpkg"
+const Apk = "apk"
+const Rpm = "rpm"
+const Semver = "semver"
+const Pep440 = "pep440"
+const Maven = "maven"
+const Generic = "generic"
+
+// packageTypeSchemes maps the anchore package types onto the version scheme
+// used by that ecosystem
+var packageTypeSchemes = map[string]string{
+	"dpkg":           Dpkg,
+	"deb":            Dpkg,
+	"apk":            Apk,
+	"apkg":           Apk,
+	"rpm":            Rpm,
+	"npm":            Semver,
+	"go":             Semver,
+	"golang":         Semver,
+	"python":         Pep440,
+	"pip":            Pep440,
+	"java":           Maven,
+	"java-archive":   Maven,
+	"maven":          Maven,
+	"jenkins-plugin": Maven,
+}
+
+// SchemeFor returns the version scheme for an anchore package type
+func SchemeFor(packageType string) string {
+	if scheme, ok := packageTypeSchemes[strings.ToLower(packageType)]; ok {
+		return scheme
+	}
+	return Generic
+}
+
+// Compare compares two versions of a package of the given anchore package type.
+// It returns -1 if a < b, 0 if a == b and 1 if a > b.
+func Compare(packageType string, a string, b string) int {
+	a = strings.TrimSpace(a)
+	b = strings.TrimSpace(b)
+	if a == b {
+		return 0
+	}
+	var result int
+	switch SchemeFor(packageType) {
+	case Dpkg:
+		result = compareDpkg(a, b)
+	case Apk:
+		result = compareApk(a, b)
+	case Rpm:
+		result = compareRpm(a, b)
+	case Semver:
+		result = compareSemver(a, b)
+	case Pep440:
+		result = comparePep440(a, b)
+	case Maven:
+		result = compareMaven(a, b)
+	default:
+		result = rpmvercmp(a, b)
+	}
+	return sign(result)
+}
+
+func sign(v int) int {
+	if v < 0 {
+		return -1
+	} else if v > 0 {
+		return 1
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// compareNumeric compares two strings of digits without converting them, so
+// arbitrarily long version components do not overflow
+func compareNumeric(a string, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
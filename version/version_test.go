@@ -0,0 +1,124 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/cloudbees-compliance/chlog-go/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type versionCase struct {
+	a        string
+	b        string
+	expected int
+}
+
+func assertOrdering(t *testing.T, packageType string, cases []versionCase) {
+	for _, c := range cases {
+		assert.Equal(t, c.expected, Compare(packageType, c.a, c.b), "%s: %s vs %s", packageType, c.a, c.b)
+		assert.Equal(t, -c.expected, Compare(packageType, c.b, c.a), "%s: %s vs %s", packageType, c.b, c.a)
+	}
+}
+
+func TestCompareDpkg(t *testing.T) {
+	log.Debug().Msg("Inside TestCompareDpkg - Enter")
+	assertOrdering(t, "dpkg", []versionCase{
+		{"1.1.1n-0+deb11u4", "1.1.1n-0+deb11u5", -1},
+		{"1.1.1n-0+deb11u3", "1.1.1n-0+deb11u3", 0},
+		{"1:1.0-1", "2.0-1", 1},
+		{"1.0~rc1-1", "1.0-1", -1},
+		{"1.0-1", "1.0-1~bpo1", 1},
+		{"247.3-7+deb11u1", "247.3-7+deb11u2", -1},
+		{"6.2+20201114-2", "6.2+20201114-2+deb11u1", -1},
+		{"1.10", "1.9", 1},
+	})
+	log.Debug().Msg("Inside TestCompareDpkg - Exit")
+}
+
+func TestCompareApk(t *testing.T) {
+	log.Debug().Msg("Inside TestCompareApk - Enter")
+	assertOrdering(t, "APKG", []versionCase{
+		{"3.0.12-r1", "3.0.12-r0", 1},
+		{"3.0.12-r1", "3.0.8-r4", 1},
+		{"1.1.1t-r0", "1.1.1s-r2", 1},
+		{"1.2_rc1-r0", "1.2-r0", -1},
+		{"1.2_p1-r0", "1.2-r0", 1},
+		{"1.2.1", "1.2", 1},
+		{"2.9.14_alpha1", "2.9.14_beta", -1},
+	})
+	log.Debug().Msg("Inside TestCompareApk - Exit")
+}
+
+func TestCompareRpm(t *testing.T) {
+	log.Debug().Msg("Inside TestCompareRpm - Enter")
+	assertOrdering(t, "rpm", []versionCase{
+		{"1.0-1.el8", "1.0-2.el8", -1},
+		{"1:1.0-1", "1.1-1", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0^git1", "1.0", 1},
+		{"1.0a", "1.0", 1},
+		{"1.010", "1.9", 1},
+		{"1.0", "1.0-5", 0},
+	})
+	log.Debug().Msg("Inside TestCompareRpm - Exit")
+}
+
+func TestCompareSemver(t *testing.T) {
+	log.Debug().Msg("Inside TestCompareSemver - Enter")
+	assertOrdering(t, "npm", []versionCase{
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.11", "1.0.0-beta.2", 1},
+		{"1.0.0+build.1", "1.0.0", 0},
+		{"2.10.0", "2.9.9", 1},
+	})
+	assertOrdering(t, "go", []versionCase{
+		{"v0.15.0", "v0.17.0", -1},
+		{"v0.0.0-20230822172742-b8732ec3820d", "v0.0.0-20230913181813-007df8e322eb", -1},
+		{"v1.58.0", "1.58.0", 0},
+	})
+	log.Debug().Msg("Inside TestCompareSemver - Exit")
+}
+
+func TestComparePep440(t *testing.T) {
+	log.Debug().Msg("Inside TestComparePep440 - Enter")
+	assertOrdering(t, "python", []versionCase{
+		{"1.0.dev1", "1.0a1", -1},
+		{"1.0a1", "1.0b1", -1},
+		{"1.0rc1", "1.0", -1},
+		{"1.0", "1.0.post1", -1},
+		{"1.0.post1.dev1", "1.0.post1", -1},
+		{"1.0", "1.0.0", 0},
+		{"1!0.5", "2.0", 1},
+		{"1.0+local.1", "1.0", 1},
+		{"1.0+abc", "1.0+1", -1},
+		{"2.31.0", "2.4.0", 1},
+	})
+	log.Debug().Msg("Inside TestComparePep440 - Exit")
+}
+
+func TestCompareMaven(t *testing.T) {
+	log.Debug().Msg("Inside TestCompareMaven - Enter")
+	assertOrdering(t, "java", []versionCase{
+		{"1.31", "1.30", 1},
+		{"1.0-alpha-1", "1.0", -1},
+		{"1.0-SNAPSHOT", "1.0", -1},
+		{"1.0-rc1", "1.0-SNAPSHOT", -1},
+		{"1.0", "1.0.0", 0},
+		{"1.0.Final", "1.0", 0},
+		{"1.0-sp1", "1.0", 1},
+		{"2.13.4.2", "2.13.4.1", 1},
+		{"5.3.20", "5.3.27", -1},
+		{"1.0a1", "1.0-alpha-1", 0},
+		{"3.19.6", "3.19.4", 1},
+	})
+	log.Debug().Msg("Inside TestCompareMaven - Exit")
+}
+
+func TestSchemeFor(t *testing.T) {
+	log.Debug().Msg("Inside TestSchemeFor - Enter")
+	assert.Equal(t, Dpkg, SchemeFor("dpkg"))
+	assert.Equal(t, Maven, SchemeFor("JAVA"))
+	assert.Equal(t, Generic, SchemeFor("binary"))
+	log.Debug().Msg("Inside TestSchemeFor - Exit")
+}